@@ -1,23 +1,246 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"prikop/internal/model"
 	"prikop/internal/orchestrator"
+	"prikop/internal/verifier"
 	"prikop/internal/worker"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 )
 
-func main() {
-	workerSocket := flag.String("worker-socket", "", "Run in worker server mode on specified socket path")
+const usage = `Usage: prikop [command] [flags]
+
+Commands:
+  optimize      full search of every phase (default)
+  recon         DPI reconnaissance only, with a diff against the previous run
+  verify        evaluate one strategy N times: prikop verify [flags] -- <nfqws args>
+                or a whole config: prikop verify -from best.json
+  minimize      drop arguments the strategy does not need: prikop minimize [flags] -- <nfqws args>
+  monitor       re-verify the deployed winners and re-optimize phases that broke
+  serve         HTTP JSON API to start, stop and watch runs, with an SSE event stream
+  export        print the last optimize result for deployment
+  bench         measure pool throughput and latency
+  worker        run the worker server (inside worker containers)
+  wg-responder  run a WireGuard responder stand-in on a host outside the DPI,
+                the target of the WireGuard phase: optimize -wg-responder host:port
+
+Run "prikop <command> -h" for the flags of a command.
+`
 
-	var cfg orchestrator.Config
-	flag.StringVar(&cfg.FakePath, "fake-path", "/app/fake", "Path to bins")
-	flag.StringVar(&cfg.TargetsPath, "targets-path", "/app/targets", "Path to targets")
+func main() {
+	// Optional subcommand before the flags: prikop recon -workers 8
+	command, args := "optimize", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
 
-	flag.Parse()
+	fs := flag.NewFlagSet("prikop "+command, flag.ExitOnError)
 
-	if *workerSocket != "" {
-		worker.RunWorkerServer(*workerSocket)
-	} else {
+	switch command {
+	case "optimize":
+		cfg := parseConfig(fs, args)
 		orchestrator.Run(cfg)
+
+	case "recon":
+		cfg := parseConfig(fs, args)
+		orchestrator.RunRecon(cfg)
+
+	case "verify":
+		groups := fs.String("group", "", "Comma-separated target groups (default: general, all phases for a multi-profile config)")
+		runs := fs.Int("runs", 3, "Evaluations per group")
+		threshold := fs.Int("threshold", model.TargetSuccessRate, "Exit with code 1 when a group passes less than this percent of checks")
+		from := fs.String("from", "", "Read the strategy from a file: result JSON, zapret config or nfqws command line")
+		cfg := parseConfig(fs, args)
+
+		strategy, groupList := "", []string(nil)
+		if *from != "" {
+			var err error
+			if strategy, groupList, err = orchestrator.ReadStrategy(*from); err != nil {
+				log.Fatalf("verify: %v", err)
+			}
+		} else {
+			strategy = strategyArg(fs)
+		}
+		if *groups != "" {
+			groupList = strings.Split(*groups, ",")
+		}
+		os.Exit(orchestrator.Verify(cfg, strategy, groupList, *runs, *threshold))
+
+	case "minimize":
+		group := fs.String("group", "general", "Target group to evaluate on")
+		runs := fs.Int("runs", 2, "Evaluations per candidate, the worst one counts")
+		cfg := parseConfig(fs, args)
+		os.Exit(orchestrator.Minimize(cfg, strategyArg(fs), *group, *runs))
+
+	case "monitor":
+		var opts orchestrator.MonitorOptions
+		fs.DurationVar(&opts.Interval, "interval", time.Hour, "Time between checks")
+		fs.IntVar(&opts.Runs, "runs", 2, "Evaluations per phase at every check")
+		fs.StringVar(&opts.HistoryPath, "monitor-history", "/app/history/monitor.jsonl", "Check history file (empty disables it)")
+		fs.IntVar(&opts.Gens, "gens", 0, "Generations of a re-optimization (default: as the phase)")
+		fs.StringVar(&opts.Webhook, "webhook", "", "URL receiving a JSON POST on degradation and re-optimization")
+		fs.StringVar(&opts.Hook, "hook", "", "Shell command run with the event JSON on stdin")
+		fs.BoolVar(&opts.Once, "once", false, "Check once and exit, code 1 if a phase stays degraded")
+		cfg := parseConfig(fs, args)
+		os.Exit(orchestrator.Monitor(cfg, opts))
+
+	case "serve":
+		listen := fs.String("listen", "127.0.0.1:8080", "HTTP API address")
+		token := fs.String("token", os.Getenv("PRIKOP_API_TOKEN"), "Bearer token required by the API (default: $PRIKOP_API_TOKEN)")
+		cfg := parseConfig(fs, args)
+		os.Exit(orchestrator.Serve(cfg, *listen, *token))
+
+	case "export":
+		format := fs.String("format", orchestrator.FormatNfqws, "Output format: nfqws, zapret, json")
+		cfg := parseConfig(fs, args)
+		os.Exit(orchestrator.Export(cfg, *format))
+
+	case "bench":
+		group := fs.String("group", "general", "Target group to evaluate on")
+		n := fs.Int("n", 0, "Number of evaluations (default: twice the pool size)")
+		cfg := parseConfig(fs, args)
+		os.Exit(orchestrator.Bench(cfg, *group, *n))
+
+	case "worker":
+		socket := fs.String("socket", "", "Unix socket path to serve on")
+		parseConfig(fs, args)
+		if *socket == "" {
+			log.Fatal("worker: -socket is required")
+		}
+		worker.RunWorkerServer(*socket)
+
+	case "wg-responder":
+		listen := fs.String("listen", ":51820", "UDP address to answer WireGuard handshakes on")
+		cfg := parseConfig(fs, args)
+		runWireGuardResponder(*listen, cfg.FakePath)
+
+	case "help":
+		fmt.Print(usage)
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+}
+
+// parseConfig registers the shared config flags and parses args in two passes:
+// the -config file is read first, explicit flags then override its values.
+func parseConfig(fs *flag.FlagSet, args []string) orchestrator.Config {
+	cfg := orchestrator.Config{
+		FakePath:    "/app/fake",
+		TargetsPath: "/app/targets",
+		Workers:     model.MaxWorkers,
+		Resolver:    verifier.ResolverSystem,
+		HistoryPath: "/app/history/recon.jsonl",
+		ResultPath:  "/app/history/best.json",
+	}
+	configPath := findConfig(args)
+	if configPath != "" {
+		if err := orchestrator.LoadConfig(configPath, &cfg); err != nil {
+			log.Fatalf("Config: %v", err)
+		}
+	}
+
+	// Defaults of the flags are the values after the config file
+	fs.String("config", "", "JSON config file, flags override its values")
+	fs.StringVar(&cfg.FakePath, "fake-path", cfg.FakePath, "Path to bins")
+	fs.StringVar(&cfg.TargetsPath, "targets-path", cfg.TargetsPath, "Path to targets")
+	fs.IntVar(&cfg.Workers, "workers", cfg.Workers, "Number of worker containers")
+	fs.BoolVar(&cfg.Adaptive, "adaptive", cfg.Adaptive, "Scale worker concurrency by error rates, timeouts and host load")
+	fs.BoolVar(&cfg.Racing, "racing", cfg.Racing, "Let workers cancel checks once the elite threshold is unreachable")
+	fs.StringVar(&cfg.Resolver, "resolver", cfg.Resolver, "Target resolver: system, doh[:url], dot[:host:port]")
+	fs.StringVar(&cfg.HistoryPath, "history", cfg.HistoryPath, "Recon history file (empty disables it)")
+	fs.StringVar(&cfg.Label, "label", cfg.Label, "ISP/ASN label for the recon history and event log, \"auto\" detects it via ipinfo.io (default: unknown)")
+	fs.StringVar(&cfg.ResultPath, "result", cfg.ResultPath, "Phase winners written by optimize, read by export")
+	fs.StringVar(&cfg.EventLog, "event-log", cfg.EventLog, "JSONL log of every evaluation: lineage, per-target results, worker (empty disables it)")
+	fs.BoolVar(&cfg.Plain, "plain", cfg.Plain, "Plain log instead of the terminal UI (always plain when stdout is not a terminal)")
+	fs.BoolVar(&cfg.AbortHopeless, "abort-hopeless", cfg.AbortHopeless, "Abort evaluations that can no longer beat the current best")
+	fs.StringVar(&cfg.DiscordVoice, "discord-voice", cfg.DiscordVoice, "Discord voice server host:port (UDP 50000-65535) of a live session, required target of the STUN phase")
+	ssrc := fs.Uint("discord-ssrc", uint(cfg.DiscordSSRC), "SSRC of the live Discord voice session, required with -discord-voice")
+	fs.StringVar(&cfg.WGResponder, "wg-responder", cfg.WGResponder, "Host[:port] of a prikop wg-responder outside the DPI, adds the WireGuard phase (default port 51820)")
+	fs.Parse(args)
+	cfg.DiscordSSRC = uint32(*ssrc)
+
+	// Workers read target group files and udp payloads from the same locations
+	verifier.TargetsDir = cfg.TargetsPath
+	verifier.PayloadDir = cfg.FakePath
+
+	if _, err := verifier.ResolverFor(cfg.Resolver); err != nil {
+		log.Fatalf("Invalid -resolver: %v", err)
+	}
+	if cfg.DiscordVoice != "" {
+		_, port, err := net.SplitHostPort(cfg.DiscordVoice)
+		if err != nil {
+			log.Fatalf("Invalid -discord-voice: %v", err)
+		}
+		// Фаза фильтрует только голосовой диапазон Discord, другой порт nfqws не увидит
+		if p, err := strconv.Atoi(port); err != nil || p < 50000 || p > 65535 {
+			log.Fatalf("Invalid -discord-voice: port %s is outside the Discord voice range 50000-65535", port)
+		}
+		// Голосовой сервер отвечает только на SSRC установленной сессии, случайный он молча отбросит
+		if cfg.DiscordSSRC == 0 {
+			log.Fatalf("-discord-voice needs -discord-ssrc of the same live voice session")
+		}
+	}
+	if cfg.WGResponder != "" {
+		if _, _, err := net.SplitHostPort(cfg.WGResponder); err != nil {
+			cfg.WGResponder = net.JoinHostPort(cfg.WGResponder, "51820")
+		}
+	}
+	return cfg
+}
+
+// findConfig looks for -config before the flag set parses anything
+func findConfig(args []string) string {
+	for i, a := range args {
+		if a == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		if !strings.HasPrefix(a, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// strategyArg joins the arguments after the flags: nfqws options start with "--",
+// so they go after a "--" separator
+func strategyArg(fs *flag.FlagSet) string {
+	strategy := strings.Join(fs.Args(), " ")
+	if strategy == "" {
+		fmt.Fprintf(os.Stderr, "%s: nfqws arguments required after --\n", fs.Name())
+		os.Exit(2)
+	}
+	return strategy
+}
+
+func runWireGuardResponder(addr, fakePath string) {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	template, err := os.ReadFile(filepath.Join(fakePath, "wireguard_response.bin"))
+	if err != nil {
+		log.Fatalf("WireGuard responder: %v", err)
+	}
+	if err := verifier.ServeWireGuard(ctx, addr, template); err != nil {
+		log.Fatalf("WireGuard responder: %v", err)
 	}
 }
@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
 	"prikop/internal/model"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/moby/moby/api/pkg/stdcopy"
@@ -24,30 +26,154 @@ type WorkerPool struct {
 	ctx         context.Context
 	size        int
 	workers     chan *Worker
+	all         []*Worker // indexed by worker slot, used by health monitor
 	containers  []string
 	socketPaths []string
 	mu          sync.Mutex
 	hostSockDir string
+	stop        chan struct{}
+	stopOnce    sync.Once
+	closed      bool
+	caps        *model.WorkerCapabilities
+
+	// dropped — воркеры, выбывшие навсегда (несовместимый образ); когда выбыли все,
+	// failed закрывается и acquire возвращает failErr вместо вечного ожидания
+	dropped int
+	failed  chan struct{}
+	failErr error
+
+	// slotFree закрывается (и заменяется новым), когда освобождается слот или растет лимит
+	slotMu   sync.Mutex
+	slotFree chan struct{}
+
+	// Adaptive concurrency: number of workers allowed to run at once (<= size)
+	limit atomic.Int64
+	busy  atomic.Int64
+	stats poolCounters
+}
+
+// PoolStats aggregates pool activity since the previous TakeStats call.
+type PoolStats struct {
+	Execs       int64 // finished evaluations
+	InfraErrors int64 // failed attempts due to worker infrastructure
+	Checks      int64 // verifier checks performed
+	Timeouts    int64 // verifier checks that ended with a timeout
+}
+
+type poolCounters struct {
+	execs, infraErrors, checks, timeouts atomic.Int64
+}
+
+// InfraRate returns the share of attempts that failed on transport level.
+func (s PoolStats) InfraRate() float64 {
+	attempts := s.Execs + s.InfraErrors
+	if attempts == 0 {
+		return 0
+	}
+	return float64(s.InfraErrors) / float64(attempts)
+}
+
+// TimeoutRate returns the share of verifier checks that timed out.
+func (s PoolStats) TimeoutRate() float64 {
+	if s.Checks == 0 {
+		return 0
+	}
+	return float64(s.Timeouts) / float64(s.Checks)
 }
 
 type Worker struct {
-	ID         string
-	SocketPath string
+	ID          string
+	SocketPath  string
+	ContainerID string
+
+	idx      int
+	failures int // consecutive transport errors, touched only by the current holder
+	dead     atomic.Bool
+}
+
+// InfraError marks a failure of the worker infrastructure (dead container, broken socket)
+// as opposed to a strategy that simply did not pass the checks.
+type InfraError struct {
+	WorkerID string
+	Err      error
+}
+
+func (e *InfraError) Error() string {
+	return fmt.Sprintf("worker %s: %v", e.WorkerID, e.Err)
+}
+
+func (e *InfraError) Unwrap() error { return e.Err }
+
+// ErrIncompatibleWorker is returned when the worker image speaks another protocol version.
+var ErrIncompatibleWorker = errors.New("incompatible worker")
+
+// ErrPoolEmpty is returned once every worker of the pool has been dropped.
+var ErrPoolEmpty = errors.New("no workers left in the pool")
+
+// IsInfraError reports whether err was caused by the worker infrastructure.
+func IsInfraError(err error) bool {
+	var ie *InfraError
+	return errors.As(err, &ie)
 }
 
 // NewWorkerPool initializes the pool.
 func NewWorkerPool(ctx context.Context, cli *client.Client, size int, hostSockDir string) *WorkerPool {
-	return &WorkerPool{
+	p := &WorkerPool{
 		cli:         cli,
 		ctx:         ctx,
 		size:        size,
 		workers:     make(chan *Worker, size),
-		containers:  make([]string, 0, size),
-		socketPaths: make([]string, 0, size),
+		all:         make([]*Worker, size),
+		containers:  make([]string, size),
+		socketPaths: make([]string, size),
 		hostSockDir: hostSockDir,
+		stop:        make(chan struct{}),
+		failed:      make(chan struct{}),
+		slotFree:    make(chan struct{}),
+	}
+	p.limit.Store(int64(size))
+	return p
+}
+
+// Size returns the number of worker containers in the pool.
+func (p *WorkerPool) Size() int { return p.size }
+
+// Limit returns the number of workers currently allowed to run concurrently.
+func (p *WorkerPool) Limit() int { return int(p.limit.Load()) }
+
+// SetLimit changes the concurrency limit, clamped to [1, Size()].
+func (p *WorkerPool) SetLimit(n int) {
+	n = max(1, min(n, p.size))
+	p.limit.Store(int64(n))
+	p.signalSlot()
+}
+
+// TakeStats returns counters accumulated since the previous call and resets them.
+func (p *WorkerPool) TakeStats() PoolStats {
+	return PoolStats{
+		Execs:       p.stats.execs.Swap(0),
+		InfraErrors: p.stats.infraErrors.Swap(0),
+		Checks:      p.stats.checks.Swap(0),
+		Timeouts:    p.stats.timeouts.Swap(0),
 	}
 }
 
+// Busy returns the number of evaluations running right now.
+func (p *WorkerPool) Busy() int { return int(p.busy.Load()) }
+
+// Healthy returns the number of workers that are not waiting for replacement.
+func (p *WorkerPool) Healthy() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, w := range p.all {
+		if w != nil && !w.dead.Load() {
+			n++
+		}
+	}
+	return n
+}
+
 func (p *WorkerPool) Start() error {
 	fmt.Printf("Initializing pool with %d workers. Host socket dir: %s\n", p.size, p.hostSockDir)
 
@@ -64,65 +190,12 @@ func (p *WorkerPool) Start() error {
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			workerName := fmt.Sprintf("prikop-worker-%d", idx)
-			workerID := fmt.Sprintf("worker_%d", idx)
-
-			sockPathInner := filepath.Join(model.SocketDir, workerID+".sock")
-			sockPathOrchestrator := filepath.Join(model.SocketDir, workerID+".sock")
-
-			// Register socket path for cleanup immediately
-			p.mu.Lock()
-			p.socketPaths = append(p.socketPaths, sockPathOrchestrator)
-			p.mu.Unlock()
-
-			// Cleanup potential stale socket/container
-			_ = os.Remove(sockPathOrchestrator)
-			_, _ = p.cli.ContainerRemove(p.ctx, workerName, client.ContainerRemoveOptions{Force: true})
-
-			createOpts := client.ContainerCreateOptions{
-				Name: workerName,
-				Config: &container.Config{
-					Image: model.ImageName,
-					Cmd:   []string{"-worker-socket", sockPathInner},
-					Tty:   false,
-				},
-				HostConfig: &container.HostConfig{
-					CapAdd: []string{"NET_ADMIN"},
-					Mounts: []mount.Mount{
-						{
-							Type:   mount.TypeBind,
-							Source: p.hostSockDir,
-							Target: model.SocketDir,
-						},
-					},
-					AutoRemove: true,
-				},
-			}
-
-			resp, err := p.cli.ContainerCreate(p.ctx, createOpts)
+			w, err := p.spawn(idx)
 			if err != nil {
-				errChan <- fmt.Errorf("create worker %d: %w", idx, err)
-				return
-			}
-
-			p.mu.Lock()
-			p.containers = append(p.containers, resp.ID)
-			p.mu.Unlock()
-
-			if _, err := p.cli.ContainerStart(p.ctx, resp.ID, client.ContainerStartOptions{}); err != nil {
-				errChan <- fmt.Errorf("start worker %d: %w", idx, err)
-				return
-			}
-
-			if err := p.waitForSocket(sockPathOrchestrator, resp.ID); err != nil {
-				errChan <- fmt.Errorf("worker %d failed to start: %w", idx, err)
+				errChan <- err
 				return
 			}
-
-			p.workers <- &Worker{
-				ID:         workerID,
-				SocketPath: sockPathOrchestrator,
-			}
+			p.workers <- w
 		}(i)
 	}
 
@@ -133,9 +206,105 @@ func (p *WorkerPool) Start() error {
 		p.Stop()
 		return <-errChan
 	}
+
+	go p.monitor()
 	return nil
 }
 
+// spawn (re)creates the container for the given slot and waits until its socket answers.
+func (p *WorkerPool) spawn(idx int) (*Worker, error) {
+	workerName := fmt.Sprintf("prikop-worker-%d", idx)
+	workerID := fmt.Sprintf("worker_%d", idx)
+
+	sockPathInner := filepath.Join(model.SocketDir, workerID+".sock")
+	sockPathOrchestrator := filepath.Join(model.SocketDir, workerID+".sock")
+
+	// Register socket path for cleanup immediately
+	p.mu.Lock()
+	p.socketPaths[idx] = sockPathOrchestrator
+	p.mu.Unlock()
+
+	// Cleanup potential stale socket/container
+	_ = os.Remove(sockPathOrchestrator)
+	_, _ = p.cli.ContainerRemove(p.ctx, workerName, client.ContainerRemoveOptions{Force: true})
+
+	createOpts := client.ContainerCreateOptions{
+		Name: workerName,
+		Config: &container.Config{
+			Image: model.ImageName,
+			Cmd:   []string{"worker", "-socket", sockPathInner},
+			Tty:   false,
+		},
+		HostConfig: &container.HostConfig{
+			CapAdd: []string{"NET_ADMIN"},
+			Mounts: []mount.Mount{
+				{
+					Type:   mount.TypeBind,
+					Source: p.hostSockDir,
+					Target: model.SocketDir,
+				},
+			},
+			AutoRemove: true,
+		},
+	}
+
+	resp, err := p.cli.ContainerCreate(p.ctx, createOpts)
+	if err != nil {
+		return nil, fmt.Errorf("create worker %d: %w", idx, err)
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		// Pool was stopped while a replacement was being created
+		p.mu.Unlock()
+		_, _ = p.cli.ContainerRemove(context.Background(), resp.ID, client.ContainerRemoveOptions{Force: true})
+		return nil, fmt.Errorf("create worker %d: pool stopped", idx)
+	}
+	p.containers[idx] = resp.ID
+	p.mu.Unlock()
+
+	if _, err := p.cli.ContainerStart(p.ctx, resp.ID, client.ContainerStartOptions{}); err != nil {
+		p.removeContainer(idx, resp.ID)
+		return nil, fmt.Errorf("start worker %d: %w", idx, err)
+	}
+
+	if err := p.waitForSocket(sockPathOrchestrator, resp.ID); err != nil {
+		p.removeContainer(idx, resp.ID)
+		return nil, fmt.Errorf("worker %d failed to start: %w", idx, err)
+	}
+
+	w := &Worker{
+		ID:          workerID,
+		SocketPath:  sockPathOrchestrator,
+		ContainerID: resp.ID,
+		idx:         idx,
+	}
+
+	if err := p.handshake(w); err != nil {
+		p.removeContainer(idx, resp.ID)
+		return nil, fmt.Errorf("worker %d handshake: %w", idx, err)
+	}
+
+	p.mu.Lock()
+	p.all[idx] = w
+	p.mu.Unlock()
+
+	return w, nil
+}
+
+// removeContainer removes a container that failed to become a worker
+func (p *WorkerPool) removeContainer(idx int, id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, _ = p.cli.ContainerRemove(ctx, id, client.ContainerRemoveOptions{Force: true})
+
+	p.mu.Lock()
+	if p.containers[idx] == id {
+		p.containers[idx] = ""
+	}
+	p.mu.Unlock()
+}
+
 func (p *WorkerPool) waitForSocket(path string, containerID string) error {
 	ticker := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
@@ -163,14 +332,98 @@ func (p *WorkerPool) waitForSocket(path string, containerID string) error {
 	}
 }
 
+// monitor periodically polls container state and flags dead workers for replacement.
+func (p *WorkerPool) monitor() {
+	ticker := time.NewTicker(model.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.Lock()
+		snapshot := append([]*Worker(nil), p.all...)
+		p.mu.Unlock()
+
+		for _, w := range snapshot {
+			if w == nil || w.dead.Load() {
+				continue
+			}
+			insp, err := p.cli.ContainerInspect(p.ctx, w.ContainerID, client.ContainerInspectOptions{})
+			if err != nil || !insp.Container.State.Running {
+				fmt.Printf("    [!] Worker %s container is down, scheduling replacement\n", w.ID)
+				w.dead.Store(true)
+			}
+		}
+	}
+}
+
+// replace recreates a quarantined worker in the background and returns it to the pool.
+func (p *WorkerPool) replace(w *Worker) {
+	for attempt := 1; ; attempt++ {
+		p.mu.Lock()
+		closed := p.closed
+		p.mu.Unlock()
+		if closed || p.ctx.Err() != nil {
+			return
+		}
+
+		nw, err := p.spawn(w.idx)
+		if err == nil {
+			fmt.Printf("    [+] Worker %s recreated\n", nw.ID)
+			p.workers <- nw
+			return
+		}
+		if errors.Is(err, ErrIncompatibleWorker) {
+			fmt.Printf("    [!] Worker %s dropped: %v\n", w.ID, err)
+			p.drop(w, err)
+			return
+		}
+
+		fmt.Printf("    [!] Recreating worker %s failed (attempt %d): %v\n", w.ID, attempt, err)
+		select {
+		case <-time.After(5 * time.Second):
+		case <-p.stop:
+			return
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// drop removes the worker from the pool for good; the last one fails the pool
+func (p *WorkerPool) drop(w *Worker, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.all[w.idx] == w {
+		p.all[w.idx] = nil
+	}
+	p.dropped++
+	if p.dropped == p.size {
+		p.failErr = fmt.Errorf("%w: %v", ErrPoolEmpty, err)
+		close(p.failed)
+	}
+}
+
 func (p *WorkerPool) Stop() {
+	p.stopOnce.Do(func() { close(p.stop) })
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	p.closed = true
 
 	ctx := context.Background()
 	var wg sync.WaitGroup
 
 	for _, cid := range p.containers {
+		if cid == "" {
+			continue
+		}
 		wg.Add(1)
 		go func(id string) {
 			defer wg.Done()
@@ -183,38 +436,295 @@ func (p *WorkerPool) Stop() {
 	wg.Wait()
 
 	for _, path := range p.socketPaths {
+		if path == "" {
+			continue
+		}
 		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 			fmt.Printf("Warning: failed to remove socket %s: %v\n", path, err)
 		}
 	}
 }
 
+// Exec runs the request on a free worker. Infrastructure failures are retried on
+// other workers up to model.MaxInfraRetries times and reported as *InfraError.
 func (p *WorkerPool) Exec(ctx context.Context, req model.WorkerRequest) (model.WorkerResult, error) {
-	select {
-	case w := <-p.workers:
-		defer func() { p.workers <- w }()
+	return p.ExecStream(ctx, req, nil)
+}
+
+// ExecStream is Exec with live worker events passed to onEvent (may be nil).
+// Cancelling ctx aborts the evaluation: the partial result collected so far is
+// returned together with ctx.Err().
+func (p *WorkerPool) ExecStream(ctx context.Context, req model.WorkerRequest, onEvent func(model.WorkerEvent)) (model.WorkerResult, error) {
+	if err := p.waitSlot(ctx); err != nil {
+		return model.WorkerResult{}, err
+	}
+	defer p.freeSlot()
 
-		d := net.Dialer{Timeout: 1 * time.Second}
-		conn, err := d.DialContext(ctx, "unix", w.SocketPath)
+	var lastErr error
+
+	for attempt := 0; attempt < model.MaxInfraRetries; attempt++ {
+		w, err := p.acquire(ctx)
 		if err != nil {
-			return model.WorkerResult{}, fmt.Errorf("dial worker %s: %w", w.ID, err)
+			return model.WorkerResult{}, err
+		}
+
+		res, err := p.stream(ctx, w, req, onEvent)
+		res.Worker = w.ID
+		if err == nil && res.Infra {
+			err = errors.New(res.Error)
+		}
+
+		// Cancelled by caller: not the worker's fault
+		if err != nil && ctx.Err() != nil {
+			p.release(w, nil)
+			return res, ctx.Err()
+		}
+
+		// Another protocol version: retrying on the same image cannot help
+		if errors.Is(err, ErrIncompatibleWorker) {
+			w.dead.Store(true)
+			go p.replace(w)
+			return res, err
+		}
+
+		p.release(w, err)
+		if err == nil {
+			p.stats.execs.Add(1)
+			p.stats.checks.Add(int64(res.TotalCount))
+			p.stats.timeouts.Add(int64(res.Timeouts))
+			return res, nil
 		}
-		defer conn.Close()
 
-		conn.SetDeadline(time.Now().Add(model.ContainerTimeout + 2*time.Second))
+		p.stats.infraErrors.Add(1)
+		lastErr = &InfraError{WorkerID: w.ID, Err: err}
+		fmt.Printf("    [!] %v (attempt %d/%d)\n", lastErr, attempt+1, model.MaxInfraRetries)
+	}
+
+	return model.WorkerResult{}, lastErr
+}
 
-		if err := json.NewEncoder(conn).Encode(req); err != nil {
-			return model.WorkerResult{}, fmt.Errorf("send req: %w", err)
+// waitSlot blocks until the number of running evaluations drops below the current limit.
+func (p *WorkerPool) waitSlot(ctx context.Context) error {
+	for {
+		// Taken before the attempt, so a slot freed in between is not missed
+		p.slotMu.Lock()
+		free := p.slotFree
+		p.slotMu.Unlock()
+
+		if p.busy.Add(1) <= p.limit.Load() {
+			return nil
 		}
+		p.busy.Add(-1)
 
-		var res model.WorkerResult
-		if err := json.NewDecoder(conn).Decode(&res); err != nil {
-			return model.WorkerResult{}, fmt.Errorf("read res: %w", err)
+		select {
+		case <-free:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
+	}
+}
+
+// freeSlot ends an evaluation and wakes up waitSlot
+func (p *WorkerPool) freeSlot() {
+	p.busy.Add(-1)
+	p.signalSlot()
+}
 
-		return res, nil
+// signalSlot wakes up every waitSlot to recheck the limit
+func (p *WorkerPool) signalSlot() {
+	p.slotMu.Lock()
+	close(p.slotFree)
+	p.slotFree = make(chan struct{})
+	p.slotMu.Unlock()
+}
 
-	case <-ctx.Done():
-		return model.WorkerResult{}, ctx.Err()
+// acquire takes a healthy worker from the pool, sending broken ones to replacement.
+func (p *WorkerPool) acquire(ctx context.Context) (*Worker, error) {
+	for {
+		select {
+		case w := <-p.workers:
+			if w.dead.Load() {
+				go p.replace(w)
+				continue
+			}
+			// Worker failed recently: make sure it is alive before trusting it with a strategy
+			if w.failures > 0 {
+				if err := p.ping(w); err != nil {
+					p.release(w, err)
+					continue
+				}
+			}
+			return w, nil
+
+		case <-p.failed:
+			return nil, p.failErr
+
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// release returns the worker to the pool or quarantines it after too many transport errors.
+func (p *WorkerPool) release(w *Worker, err error) {
+	if err == nil {
+		w.failures = 0
+		p.workers <- w
+		return
+	}
+
+	w.failures++
+	if w.failures >= model.MaxWorkerFailures || w.dead.Load() {
+		fmt.Printf("    [!] Worker %s quarantined after %d failures: %v\n", w.ID, w.failures, err)
+		w.dead.Store(true)
+		go p.replace(w)
+		return
 	}
+	p.workers <- w
+}
+
+// handshake checks the worker protocol version and records its capabilities.
+func (p *WorkerPool) handshake(w *Worker) error {
+	res, err := p.roundTrip(p.ctx, w, model.WorkerRequest{Type: model.RequestHello}, model.ContainerTimeout)
+	if err != nil {
+		return err
+	}
+
+	// Workers built before the handshake existed ignore the request type and answer without capabilities
+	if res.Capabilities == nil {
+		return fmt.Errorf("%w: worker does not support handshake (protocol v0), orchestrator expects v%d; rebuild %s",
+			ErrIncompatibleWorker, model.ProtocolVersion, model.ImageName)
+	}
+	if res.Capabilities.ProtocolVersion != model.ProtocolVersion {
+		return fmt.Errorf("%w: worker speaks protocol v%d, orchestrator expects v%d; rebuild %s",
+			ErrIncompatibleWorker, res.Capabilities.ProtocolVersion, model.ProtocolVersion, model.ImageName)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.caps == nil {
+		p.caps = res.Capabilities
+	} else if p.caps.NfqwsVersion != res.Capabilities.NfqwsVersion {
+		fmt.Printf("Warning: worker %s runs %q, pool runs %q\n", w.ID, res.Capabilities.NfqwsVersion, p.caps.NfqwsVersion)
+	}
+	return nil
+}
+
+// Capabilities returns what the workers reported during the handshake.
+func (p *WorkerPool) Capabilities() model.WorkerCapabilities {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.caps == nil {
+		return model.WorkerCapabilities{}
+	}
+	return *p.caps
+}
+
+func (p *WorkerPool) ping(w *Worker) error {
+	res, err := p.roundTrip(p.ctx, w, model.WorkerRequest{Type: model.RequestPing}, model.PingTimeout)
+	if err != nil {
+		return err
+	}
+	if !res.Success {
+		return fmt.Errorf("ping rejected: %s", res.Error)
+	}
+	return nil
+}
+
+// stream sends a run request and consumes the worker's NDJSON events until the final result.
+func (p *WorkerPool) stream(ctx context.Context, w *Worker, req model.WorkerRequest, onEvent func(model.WorkerEvent)) (model.WorkerResult, error) {
+	conn, err := p.send(ctx, w, req, model.ContainerTimeout+2*time.Second)
+	if err != nil {
+		return model.WorkerResult{}, err
+	}
+	defer conn.Close()
+
+	// Closing the connection tells the worker to abort and unblocks our reads
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	partial := model.WorkerResult{Aborted: true}
+	dec := json.NewDecoder(conn)
+
+	for {
+		var line json.RawMessage
+		if err := dec.Decode(&line); err != nil {
+			return partial, fmt.Errorf("read event: %w", err)
+		}
+		var ev model.WorkerEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return partial, fmt.Errorf("parse event: %w", err)
+		}
+		// A plain result instead of events: the worker rejected the request
+		// before streaming (protocol mismatch, unreadable request)
+		if ev.Event == "" {
+			var res model.WorkerResult
+			_ = json.Unmarshal(line, &res)
+			return partial, fmt.Errorf("%w: %s", ErrIncompatibleWorker, res.Error)
+		}
+		if onEvent != nil {
+			onEvent(ev)
+		}
+
+		switch ev.Event {
+		case model.EventNfqwsStarted:
+			partial.TotalCount = ev.Total
+			partial.TotalWeight = ev.Weight
+		case model.EventTarget:
+			if ev.Target == nil {
+				continue
+			}
+			partial.Targets = append(partial.Targets, *ev.Target)
+			if ev.Target.Passed {
+				partial.SuccessCount++
+				partial.Weight += ev.Target.Weight
+				partial.Passed = append(partial.Passed, ev.Target.URL)
+			} else {
+				partial.Failed = append(partial.Failed, ev.Target.URL)
+			}
+		case model.EventNfqwsExited:
+		case model.EventResult:
+			if ev.Result == nil {
+				return partial, errors.New("empty result event")
+			}
+			return *ev.Result, nil
+		default:
+			return partial, fmt.Errorf("unexpected event %q", ev.Event)
+		}
+	}
+}
+
+// send dials the worker and writes the request, returning the open connection.
+func (p *WorkerPool) send(ctx context.Context, w *Worker, req model.WorkerRequest, timeout time.Duration) (net.Conn, error) {
+	d := net.Dialer{Timeout: 1 * time.Second}
+	conn, err := d.DialContext(ctx, "unix", w.SocketPath)
+	if err != nil {
+		return nil, fmt.Errorf("dial worker %s: %w", w.ID, err)
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	req.Version = model.ProtocolVersion
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("send req: %w", err)
+	}
+	return conn, nil
+}
+
+// roundTrip performs a single request/response exchange (ping, hello).
+func (p *WorkerPool) roundTrip(ctx context.Context, w *Worker, req model.WorkerRequest, timeout time.Duration) (model.WorkerResult, error) {
+	conn, err := p.send(ctx, w, req, timeout)
+	if err != nil {
+		return model.WorkerResult{}, err
+	}
+	defer conn.Close()
+
+	var res model.WorkerResult
+	if err := json.NewDecoder(conn).Decode(&res); err != nil {
+		return model.WorkerResult{}, fmt.Errorf("read res: %w", err)
+	}
+
+	return res, nil
 }
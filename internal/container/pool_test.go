@@ -0,0 +1,96 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// The semaphore and the drop accounting need no docker: the pool is built with a nil client
+
+func TestSetLimit(t *testing.T) {
+	p := NewWorkerPool(context.Background(), nil, 4, "")
+	cases := []struct {
+		n, want int
+	}{
+		{2, 2},
+		{4, 4},
+		{9, 4},
+		{0, 1},
+		{-3, 1},
+	}
+	for _, c := range cases {
+		p.SetLimit(c.n)
+		if got := p.Limit(); got != c.want {
+			t.Errorf("SetLimit(%d): limit %d, want %d", c.n, got, c.want)
+		}
+	}
+}
+
+func TestWaitSlot(t *testing.T) {
+	p := NewWorkerPool(context.Background(), nil, 2, "")
+	p.SetLimit(1)
+
+	if err := p.waitSlot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The limit is taken: a second evaluation waits until its context ends
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.waitSlot(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("waitSlot over the limit: %v, want deadline exceeded", err)
+	}
+	if got := p.Busy(); got != 1 {
+		t.Fatalf("busy %d after a timed out wait, want 1", got)
+	}
+
+	wakers := []struct {
+		name string
+		wake func()
+	}{
+		{"freeSlot", p.freeSlot},
+		{"SetLimit", func() { p.SetLimit(2) }},
+	}
+	for _, w := range wakers {
+		done := make(chan error, 1)
+		go func() { done <- p.waitSlot(context.Background()) }()
+
+		select {
+		case err := <-done:
+			t.Fatalf("%s: waitSlot returned %v before a slot was free", w.name, err)
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		w.wake()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("%s: %v", w.name, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s did not wake the waiting evaluation", w.name)
+		}
+	}
+	if got := p.Busy(); got != 2 {
+		t.Fatalf("busy %d, want 2", got)
+	}
+}
+
+func TestDropFailsEmptyPool(t *testing.T) {
+	p := NewWorkerPool(context.Background(), nil, 2, "")
+	cause := errors.New("protocol mismatch")
+
+	p.drop(&Worker{idx: 0}, cause)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquire with a worker left: %v, want deadline exceeded", err)
+	}
+
+	p.drop(&Worker{idx: 1}, cause)
+	if _, err := p.acquire(context.Background()); !errors.Is(err, ErrPoolEmpty) {
+		t.Fatalf("acquire from an emptied pool: %v, want %v", err, ErrPoolEmpty)
+	}
+}
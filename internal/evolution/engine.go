@@ -1,6 +1,7 @@
 package evolution
 
 import (
+	"fmt"
 	"math/rand"
 	"sort"
 
@@ -11,12 +12,37 @@ import (
 const (
 	PopulationSize = 100
 	ElitesCount    = 50
+	// RequiredPenalty больше любого successRate, поэтому провал обязательной цели не компенсировать
+	RequiredPenalty = 200.0
 )
 
+// Операторы, которыми получена особь
+const (
+	OpGalaxy    = "galaxy" // нулевое поколение
+	OpSeed      = "seed"   // затравка, например прежний победитель
+	OpElite     = "elite"
+	OpMutation  = "mutation"
+	OpCrossover = "crossover"
+	OpFresh     = "fresh" // случайная стратегия для добора популяции
+)
+
+// Individual — стратегия поколения вместе с происхождением
+type Individual struct {
+	Strategy nfqws.Strategy
+	// Parents — ID родителей из предыдущего поколения (ScoredStrategy.ID)
+	Parents  []string
+	Operator string
+}
+
+// IndividualID names the idx-th individual of a generation
+func IndividualID(gen, idx int) string {
+	return fmt.Sprintf("%d.%d", gen, idx)
+}
+
 // Evolve принимает результаты прошлого поколения и возвращает новое строго фиксированного размера
-func Evolve(results []model.ScoredStrategy, discoveredBins []string) []nfqws.Strategy {
-	var nextGen []nfqws.Strategy
-	mutator := NewMutator(discoveredBins)
+func Evolve(results []model.ScoredStrategy, discoveredBins []string, report model.ReconReport) []Individual {
+	var nextGen []Individual
+	mutator := NewMutator(discoveredBins, report)
 
 	// 1. Сортировка (на всякий случай, если оркестратор не отсортировал)
 	sort.Slice(results, func(i, j int) bool {
@@ -27,7 +53,7 @@ func Evolve(results []model.ScoredStrategy, discoveredBins []string) []nfqws.Str
 	// 2. Elitism: Сохраняем лучших без изменений
 	for i := 0; i < len(results) && i < ElitesCount; i++ {
 		if s, ok := results[i].Config.(nfqws.Strategy); ok {
-			nextGen = append(nextGen, s)
+			nextGen = append(nextGen, Individual{Strategy: s, Parents: []string{results[i].ID}, Operator: OpElite})
 		}
 	}
 
@@ -47,7 +73,7 @@ func Evolve(results []model.ScoredStrategy, discoveredBins []string) []nfqws.Str
 		for k := 0; k < 3; k++ {
 			child := parent
 			mutator.Mutate(&child)
-			nextGen = append(nextGen, child)
+			nextGen = append(nextGen, Individual{Strategy: child, Parents: []string{results[i].ID}, Operator: OpMutation})
 		}
 	}
 
@@ -65,11 +91,18 @@ func Evolve(results []model.ScoredStrategy, discoveredBins []string) []nfqws.Str
 				// Скрещиваем параметры Fake и TTL
 				child.Fake = p2.Fake
 				child.TTL = p2.TTL
+				if rand.Intn(2) == 0 {
+					child.Tamper = p2.Tamper
+				}
 				// Шанс мутации ребенка
 				if rand.Float64() < 0.3 {
 					mutator.Mutate(&child)
 				}
-				nextGen = append(nextGen, child)
+				nextGen = append(nextGen, Individual{
+					Strategy: child,
+					Parents:  []string{results[idx1].ID, results[idx2].ID},
+					Operator: OpCrossover,
+				})
 			}
 		}
 	}
@@ -87,7 +120,7 @@ func Evolve(results []model.ScoredStrategy, discoveredBins []string) []nfqws.Str
 			Repeats: 1 + rand.Intn(5),
 		}
 		mutator.Mutate(&newStrat) // Полная рандомизация
-		nextGen = append(nextGen, newStrat)
+		nextGen = append(nextGen, Individual{Strategy: newStrat, Operator: OpFresh})
 	}
 
 	return nextGen
@@ -97,11 +130,61 @@ func CalculateScore(res model.WorkerResult, complexity int) float64 {
 	if res.TotalCount == 0 {
 		return 0
 	}
-	// Приоритет: SuccessRate > Code 200 > Low Complexity
-	successRate := (float64(res.SuccessCount) / float64(res.TotalCount)) * 100.0
+	// Приоритет: Required > взвешенный SuccessRate > Code 200 > Low Complexity
+	// Проваленные цели дают частичный зачет, если DPI уже пропустил рукопожатие
+	total := res.TotalWeight
+	passed := res.Weight
+	if total == 0 {
+		// Результат без весов: все цели равноценны
+		total = float64(res.TotalCount)
+		passed = float64(res.SuccessCount)
+	}
+	// Частичный зачет масштабируется так, что в сумме он меньше веса самой легкой цели:
+	// лишний пройденный таргет всегда важнее любого числа "почти прошедших"
+	progress, lightest := 0.0, 0.0
+	for _, t := range res.Targets {
+		w := targetWeight(t)
+		if lightest == 0 || w < lightest {
+			lightest = w
+		}
+		if !t.Passed {
+			progress += progressCredit(t.Class) * w
+		}
+	}
+	if progress > 0 {
+		passed += progress * lightest / (total + lightest)
+	}
+	successRate := (passed / total) * 100.0
+
+	// Провал обязательной цели опускает стратегию ниже любой, прошедшей все обязательные
+	if len(res.RequiredFailed()) > 0 {
+		successRate -= RequiredPenalty
+	}
 
 	// Штраф за сложность (repeats) минимален, но важен при равных успехах
 	penalty := float64(complexity) * 0.1
 
 	return successRate - penalty
 }
+
+func targetWeight(t model.TargetResult) float64 {
+	if t.Weight <= 0 {
+		return 1
+	}
+	return t.Weight
+}
+
+// progressCredit — доля цели, засчитываемая за провал данного класса,
+// до масштабирования в CalculateScore.
+func progressCredit(class string) float64 {
+	switch class {
+	case model.ClassStalled, model.ClassShort, model.ClassStatus, model.ClassSlow:
+		// Рукопожатие прошло, сервер отвечал
+		return 0.3
+	case model.ClassTimeout, model.ClassReset:
+		// Рукопожатие прошло, ответа нет
+		return 0.15
+	default:
+		return 0
+	}
+}
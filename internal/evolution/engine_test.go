@@ -0,0 +1,127 @@
+package evolution
+
+import (
+	"testing"
+
+	"prikop/internal/model"
+)
+
+// result builds a worker result the way the worker reports it: counts and weights from the targets
+func result(targets ...model.TargetResult) model.WorkerResult {
+	res := model.WorkerResult{Targets: targets, TotalCount: len(targets)}
+	for _, t := range targets {
+		res.TotalWeight += targetWeight(t)
+		if t.Passed {
+			res.SuccessCount++
+			res.Weight += targetWeight(t)
+		}
+	}
+	return res
+}
+
+func pass(url string) model.TargetResult {
+	return model.TargetResult{URL: url, Passed: true}
+}
+
+func fail(url, class string) model.TargetResult {
+	return model.TargetResult{URL: url, Class: class}
+}
+
+func TestCalculateScorePartialCredit(t *testing.T) {
+	cases := []struct {
+		name          string
+		better, worse model.WorkerResult
+	}{
+		{
+			"handshake passed beats dial failure",
+			result(pass("a"), fail("b", model.ClassStalled)),
+			result(pass("a"), fail("b", model.ClassDial)),
+		},
+		{
+			"server answered beats silence",
+			result(pass("a"), fail("b", model.ClassShort)),
+			result(pass("a"), fail("b", model.ClassTimeout)),
+		},
+		{
+			"an extra pass beats any number of near passes",
+			result(pass("a"), pass("b"), fail("c", model.ClassDial), fail("d", model.ClassDial), fail("e", model.ClassDial)),
+			result(pass("a"), fail("b", model.ClassStalled), fail("c", model.ClassStalled), fail("d", model.ClassStalled), fail("e", model.ClassStalled)),
+		},
+		{
+			"credit stays below the lightest target",
+			result(pass("a"), fail("b", model.ClassDial), model.TargetResult{URL: "c", Passed: true, Weight: 0.5}),
+			result(pass("a"), fail("b", model.ClassStalled), model.TargetResult{URL: "c", Class: model.ClassStalled, Weight: 0.5}),
+		},
+	}
+	for _, c := range cases {
+		better, worse := CalculateScore(c.better, 0), CalculateScore(c.worse, 0)
+		if better <= worse {
+			t.Errorf("%s: %.3f <= %.3f", c.name, better, worse)
+		}
+	}
+}
+
+func TestCalculateScoreValues(t *testing.T) {
+	cases := []struct {
+		name       string
+		res        model.WorkerResult
+		complexity int
+		want       float64
+	}{
+		{"no targets", model.WorkerResult{}, 3, 0},
+		{"all passed", result(pass("a"), pass("b")), 0, 100},
+		{"complexity penalty", result(pass("a"), pass("b")), 4, 99.6},
+		{"dial failure gets nothing", result(pass("a"), fail("b", model.ClassDial)), 0, 50},
+		// 0.3 credit scaled by lightest/(total+lightest) = 1/3 of one target out of two
+		{"stalled credit", result(pass("a"), fail("b", model.ClassStalled)), 0, 55},
+		{"unweighted result", model.WorkerResult{TotalCount: 4, SuccessCount: 3}, 0, 75},
+	}
+	for _, c := range cases {
+		if got := CalculateScore(c.res, c.complexity); got < c.want-1e-9 || got > c.want+1e-9 {
+			t.Errorf("%s: score %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestCalculateScoreRequired(t *testing.T) {
+	required := model.TargetResult{URL: "req", Passed: true, Required: true}
+	requiredFailed := model.TargetResult{URL: "req", Class: model.ClassTimeout, Required: true}
+	heavy := func(passed bool) model.TargetResult {
+		return model.TargetResult{URL: "heavy", Passed: passed, Weight: 5}
+	}
+
+	// An aborted evaluation that never reached the must-pass target
+	aborted := result(pass("a"), pass("b"))
+	aborted.FillMissing([]model.Target{{URL: "a"}, {URL: "b"}, {URL: "req", Required: true}})
+
+	cases := []struct {
+		name          string
+		better, worse model.WorkerResult
+	}{
+		{
+			"required pass beats everything else passing",
+			result(required, fail("a", model.ClassDial), fail("b", model.ClassDial), heavy(false)),
+			result(requiredFailed, pass("a"), pass("b"), heavy(true)),
+		},
+		{
+			"heavier target outweighs lighter ones",
+			result(fail("a", model.ClassDial), fail("b", model.ClassDial), heavy(true)),
+			result(pass("a"), pass("b"), heavy(false)),
+		},
+		{
+			"unreached required target counts as failed",
+			result(pass("a"), fail("b", model.ClassDial), required),
+			aborted,
+		},
+	}
+	for _, c := range cases {
+		better, worse := CalculateScore(c.better, 0), CalculateScore(c.worse, 0)
+		if better <= worse {
+			t.Errorf("%s: %.3f <= %.3f", c.name, better, worse)
+		}
+	}
+
+	if got := CalculateScore(result(requiredFailed, pass("a")), 0); got >= 0 {
+		t.Errorf("required failure: score %.3f, want below zero", got)
+	}
+}
@@ -4,15 +4,18 @@ import (
 	"math/rand"
 	"strings"
 
+	"prikop/internal/model"
 	"prikop/internal/nfqws"
 )
 
 type Mutator struct {
 	AvailableBins []string
+	// Report — результаты разведки, сужают пространство поиска
+	Report model.ReconReport
 }
 
-func NewMutator(bins []string) *Mutator {
-	return &Mutator{AvailableBins: bins}
+func NewMutator(bins []string, report model.ReconReport) *Mutator {
+	return &Mutator{AvailableBins: bins, Report: report}
 }
 
 // Mutate implements Grammar-Based Fuzzing (Constraint Enforcement).
@@ -36,12 +39,14 @@ func (m *Mutator) Mutate(s *nfqws.Strategy) {
 	} else {
 		// 3. Global Tuning (40%)
 		subR := rand.Float64()
-		if subR < 0.33 {
+		if subR < 0.25 {
 			m.mutateRepeats(s)
-		} else if subR < 0.66 {
+		} else if subR < 0.50 {
 			m.mutateFooling(s)
-		} else {
+		} else if subR < 0.75 {
 			m.mutateTTL(s)
+		} else {
+			m.mutateTamper(s)
 		}
 	}
 
@@ -50,6 +55,11 @@ func (m *Mutator) Mutate(s *nfqws.Strategy) {
 
 // sanitize enforces the CFG constraints.
 func (m *Mutator) sanitize(s *nfqws.Strategy) {
+	// Без режима десинхронизации fooling, ttl и сплит не делают ничего
+	if s.Mode == "" {
+		m.mutateMode(s)
+	}
+
 	isFake := s.Mode == "fake"
 	isSplit := s.Mode == "multisplit" || s.Mode == "fakedsplit" || s.Mode == "multidisorder" || s.Mode == "ipfrag1"
 
@@ -57,19 +67,62 @@ func (m *Mutator) sanitize(s *nfqws.Strategy) {
 		s.Fake = nfqws.FakeOptions{}
 	}
 
+	// hostspell требует ровно 4 символа
+	if len(s.Tamper.HostSpell) != 4 {
+		s.Tamper.HostSpell = ""
+	}
+
 	if !isSplit {
 		s.Split = nfqws.SplitOptions{}
 	}
 
 	// Self-repair: Ensure minimal valid configuration
-	if isFake && s.Fake.TLS == "" && s.Fake.Quic == "" && len(m.AvailableBins) > 0 {
+	if isFake && s.Fake.TLS == "" && s.Fake.Quic == "" && s.Fake.Http == "" && len(m.AvailableBins) > 0 {
 		m.mutateFake(s)
 	}
 }
 
+// IgnoredWeight — относительная вероятность техники, на которую DPI по разведке не реагирует
+const IgnoredWeight = 0.1
+
+// techWeight down-weights techniques the recon fingerprint proved useless
+func (m *Mutator) techWeight(tech string) float64 {
+	if m.Report.Ignored(tech) {
+		return IgnoredWeight
+	}
+	return 1
+}
+
+// pickWeighted returns a random item with probability proportional to its weight
+func pickWeighted(items []string, weight func(string) float64) string {
+	total := 0.0
+	for _, it := range items {
+		total += weight(it)
+	}
+	r := rand.Float64() * total
+	for _, it := range items {
+		r -= weight(it)
+		if r < 0 {
+			return it
+		}
+	}
+	return items[len(items)-1]
+}
+
 func (m *Mutator) mutateMode(s *nfqws.Strategy) {
+	modeTech := map[string]string{
+		"fake":          model.TechFake,
+		"multisplit":    model.TechSplitPos1,
+		"multidisorder": model.TechDisorder,
+		"fakedsplit":    model.TechFake,
+	}
 	modes := []string{"fake", "multisplit", "multidisorder", "fakedsplit", "ipfrag1"}
-	s.Mode = modes[rand.Intn(len(modes))]
+	s.Mode = pickWeighted(modes, func(mode string) float64 {
+		if mode == "ipfrag1" && len(m.Report.Fingerprint) > 0 && !m.Report.IPFragWorks {
+			return IgnoredWeight
+		}
+		return m.techWeight(modeTech[mode])
+	})
 }
 
 func (m *Mutator) mutateRepeats(s *nfqws.Strategy) {
@@ -92,14 +145,19 @@ func (m *Mutator) mutateFake(s *nfqws.Strategy) {
 	// Reset fields to avoid conflict
 	s.Fake.TLS = ""
 	s.Fake.Quic = ""
+	s.Fake.Http = ""
 	s.Fake.TlsMod = ""
 
 	// Content-Aware Assignment
 	// Check filename heuristics to determine capability
 	isTLS := strings.Contains(bin, "tls") || strings.Contains(bin, "clienthello")
 	isQUIC := strings.Contains(bin, "quic")
+	isHTTP := strings.Contains(bin, "http")
 
-	if isTLS {
+	if isHTTP && !isTLS {
+		// Plain HTTP request: only meaningful as fake-http, no TLS modifiers
+		s.Fake.Http = bin
+	} else if isTLS {
 		// It's a TLS packet: Use fake-tls and allow TLS modifiers
 		s.Fake.TLS = bin
 		mods := []string{"", "rnd", "rndsni"}
@@ -126,11 +184,16 @@ func (m *Mutator) mutateFake(s *nfqws.Strategy) {
 func (m *Mutator) mutateSplit(s *nfqws.Strategy) {
 	if rand.Float64() < 0.5 {
 		positions := []string{"1", "2", "3", "1,sniext+1", "2,sniext+1", "1,midsld"}
-		s.Split.Pos = positions[rand.Intn(len(positions))]
+		s.Split.Pos = pickWeighted(positions, func(pos string) float64 {
+			if strings.Contains(pos, "sni") || strings.Contains(pos, "sld") {
+				return m.techWeight(model.TechSplitSNI)
+			}
+			return m.techWeight(model.TechSplitPos1)
+		})
 	}
 
 	if rand.Float64() < 0.5 {
-		if rand.Intn(2) == 0 {
+		if rand.Float64() >= 0.5*m.techWeight(model.TechSeqOvl) {
 			s.Split.SeqOvl = 0
 			s.Split.Pattern = ""
 		} else {
@@ -143,6 +206,18 @@ func (m *Mutator) mutateSplit(s *nfqws.Strategy) {
 }
 
 func (m *Mutator) mutateTTL(s *nfqws.Strategy) {
+	// Known distance to the DPI: fixed TTL within one hop of it, autottl only sometimes
+	if d := m.Report.TTLDistance; d > 0 {
+		if rand.Float64() < 0.75 {
+			s.TTL.Fixed = max(1, d+rand.Intn(3)-1)
+			s.TTL.Auto = 0
+		} else {
+			s.TTL.Auto = rand.Intn(3) + 1
+			s.TTL.Fixed = 0
+		}
+		return
+	}
+
 	if rand.Intn(2) == 0 {
 		s.TTL.Fixed = rand.Intn(10) + 1
 		s.TTL.Auto = 0
@@ -152,14 +227,41 @@ func (m *Mutator) mutateTTL(s *nfqws.Strategy) {
 	}
 }
 
-func (m *Mutator) mutateFooling(s *nfqws.Strategy) {
+// mutateTamper flips the HTTP header tampering options, they only affect plain HTTP
+func (m *Mutator) mutateTamper(s *nfqws.Strategy) {
+	if rand.Float64() < 0.3 {
+		s.Tamper.HostCase = !s.Tamper.HostCase
+	}
 	if rand.Float64() < 0.3 {
-		s.Fooling.Md5Sig = !s.Fooling.Md5Sig
+		spells := []string{"", "hoSt", "HOST", "HoSt"}
+		s.Tamper.HostSpell = spells[rand.Intn(len(spells))]
 	}
 	if rand.Float64() < 0.3 {
-		s.Fooling.BadSum = !s.Fooling.BadSum
+		s.Tamper.HostNoSpace = !s.Tamper.HostNoSpace
 	}
 	if rand.Float64() < 0.3 {
-		s.Fooling.BadSeq = !s.Fooling.BadSeq
+		s.Tamper.DomCase = !s.Tamper.DomCase
+	}
+	if rand.Float64() < 0.3 {
+		s.Tamper.MethodEol = !s.Tamper.MethodEol
+	}
+}
+
+func (m *Mutator) mutateFooling(s *nfqws.Strategy) {
+	m.toggle(&s.Fooling.Md5Sig, model.TechMd5Sig)
+	m.toggle(&s.Fooling.BadSum, model.TechBadSum)
+	m.toggle(&s.Fooling.BadSeq, model.TechBadSeq)
+	m.toggle(&s.Fooling.Ts, model.TechTs)
+	m.toggle(&s.Fooling.Datanoack, model.TechDatanoack)
+}
+
+// toggle flips a fooling flag with 30% chance; switching on an ignored technique is rarer
+func (m *Mutator) toggle(flag *bool, tech string) {
+	p := 0.3
+	if !*flag {
+		p *= m.techWeight(tech)
+	}
+	if rand.Float64() < p {
+		*flag = !*flag
 	}
 }
@@ -1,12 +1,15 @@
 package galaxy
 
 import (
+	"strings"
+
 	"prikop/internal/model"
 	"prikop/internal/nfqws"
 )
 
-// GenerateZeroGeneration создает "выстрелы" по галактике: перебор bin-файлов в разных режимах
-func GenerateZeroGeneration(discoveredBins []string, report model.ReconReport) []nfqws.Strategy {
+// GenerateZeroGeneration создает "выстрелы" по галактике: перебор bin-файлов в разных режимах.
+// tcp — фаза фильтрует TCP, и в нее стоит сеять подмену заголовка Host
+func GenerateZeroGeneration(discoveredBins []string, report model.ReconReport, tcp bool) []nfqws.Strategy {
 	var population []nfqws.Strategy
 
 	// 1. Naked Checks (Базовые режимы без фейков)
@@ -15,6 +18,17 @@ func GenerateZeroGeneration(discoveredBins []string, report model.ReconReport) [
 		nfqws.Strategy{Mode: "multidisorder", Split: nfqws.SplitOptions{Pos: "1"}, Repeats: 2, WSS: nfqws.WSSOptions{Enabled: true}},
 	)
 
+	// Host header tampering поверх сплита по методу: в UDP-фазах HTTP нет, а геном
+	// без --dpi-desync мутации только обвешивают параметрами, которые nfqws не применит
+	if tcp {
+		split := nfqws.SplitOptions{Pos: "method+2"}
+		population = append(population,
+			nfqws.Strategy{Mode: "multisplit", Split: split, Repeats: 2, Tamper: nfqws.TamperOptions{HostCase: true}},
+			nfqws.Strategy{Mode: "multisplit", Split: split, Repeats: 2, Tamper: nfqws.TamperOptions{HostSpell: "hoSt", HostNoSpace: true}},
+			nfqws.Strategy{Mode: "multisplit", Split: split, Repeats: 2, Tamper: nfqws.TamperOptions{DomCase: true, MethodEol: true}},
+		)
+	}
+
 	// Pruning: Only add ipfrag1 if Recon confirmed it works
 	if report.IPFragWorks {
 		population = append(population, nfqws.Strategy{Mode: "ipfrag1", Repeats: 2})
@@ -36,6 +50,16 @@ func GenerateZeroGeneration(discoveredBins []string, report model.ReconReport) [
 			Fake:    nfqws.FakeOptions{TLS: binPath, TlsMod: "rndsni"},
 		})
 
+		// Гипотеза A': тот же фейк, но с TTL ровно до DPI (если разведка нашла дистанцию)
+		if d := report.TTLDistance; d > 0 && strings.Contains(binPath, "tls") {
+			population = append(population, nfqws.Strategy{
+				Mode:    "fake",
+				Repeats: 4,
+				TTL:     nfqws.TTLOptions{Fixed: d},
+				Fake:    nfqws.FakeOptions{TLS: binPath, TlsMod: "rndsni"},
+			})
+		}
+
 		// Гипотеза B: Fake Quic (если бинарник похож на QUIC, хотя пробуем все)
 		foolingB := nfqws.FoolingSet{Md5Sig: true}
 		if report.BadSumWorks {
@@ -49,6 +73,17 @@ func GenerateZeroGeneration(discoveredBins []string, report model.ReconReport) [
 			Fake:    nfqws.FakeOptions{Quic: binPath, TlsMod: "rnd"},
 		})
 
+		// Гипотеза HTTP: fake-http с подменой регистра Host
+		if strings.Contains(binPath, "http") && !strings.Contains(binPath, "tls") {
+			population = append(population, nfqws.Strategy{
+				Mode:    "fake",
+				Repeats: 4,
+				Fooling: foolingB,
+				Fake:    nfqws.FakeOptions{Http: binPath},
+				Tamper:  nfqws.TamperOptions{DomCase: true},
+			})
+		}
+
 		// Гипотеза C: Disorder/Split с этим бинарником как split-pattern (оверлей)
 		population = append(population, nfqws.Strategy{
 			Mode:    "multisplit",
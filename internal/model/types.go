@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 )
 
@@ -9,16 +10,92 @@ const (
 	ImageName         = "prikop:latest"
 	ContainerTimeout  = 15 * time.Second
 	MaxWorkers        = 50
+	MinWorkers        = 4
 	CheckTimeout      = 4000 * time.Millisecond
 	TargetSuccessRate = 80
+	// MaxWorkerFailures - число подряд идущих транспортных ошибок, после которого воркер пересоздается
+	MaxWorkerFailures = 3
+	// MaxInfraRetries - сколько раз повторять оценку при ошибке инфраструктуры
+	MaxInfraRetries     = 3
+	HealthCheckInterval = 10 * time.Second
+	PingTimeout         = 2 * time.Second
 	// SocketDir - директория для сокетов внутри контейнеров
 	SocketDir = "/var/run/prikop"
+	// DNSMark - fwmark запросов DoH/DoT воркера, такие пакеты не идут в NFQUEUE
+	DNSMark = 0x20000000
+)
+
+// ProtocolVersion — версия протокола оркестратор <-> воркер.
+// Увеличивать при любом несовместимом изменении WorkerRequest/WorkerResult.
+const ProtocolVersion = 3
+
+// Типы запросов к воркеру. Пустой тип означает прогон стратегии.
+const (
+	RequestRun   = ""
+	RequestPing  = "ping"
+	RequestHello = "hello"
 )
 
 // WorkerRequest отправляется оркестратором воркеру
 type WorkerRequest struct {
+	Version      int    `json:"version"`
+	Type         string `json:"type,omitempty"`
 	StrategyArgs string `json:"strategy_args"`
 	TargetGroup  string `json:"target_group"`
+	// MinWeight > 0 включает режим гонки на стороне верификатора
+	MinWeight float64 `json:"min_weight,omitempty"`
+	// CheckMode выбирает режим проверки ("" — обычный, "freeze" — детектор заморозки, "dns" — сверка резолверов)
+	CheckMode string `json:"check_mode,omitempty"`
+	// MinThroughput (байт/с) > 0 — крупные цели медленнее этого считаются проваленными
+	MinThroughput int64 `json:"min_throughput,omitempty"`
+	// Targets переопределяет цели группы (из файла фазы); пусто — воркер читает свой targets/<group>.json
+	Targets []Target `json:"targets,omitempty"`
+	// Resolver — как резолвить цели: "" или "system", "doh[:url]", "dot[:host:port]"
+	Resolver string `json:"resolver,omitempty"`
+	// Baseline — проверка без NFQUEUE и nfqws: доступны ли цели вообще без обхода
+	Baseline bool `json:"baseline,omitempty"`
+}
+
+// Target — цель проверки, описывается в targets/<group>.json
+type Target struct {
+	URL          string `json:"url"`
+	Proto        string `json:"proto,omitempty"` // tcp (по умолчанию), quic, stun, discord_ip, udp
+	Threshold    int    `json:"threshold"`       // байт для успеха
+	IgnoreStatus bool   `json:"ignore_status,omitempty"`
+	// ReadLimit > Threshold — крупная цель: читаем дальше порога, чтобы замерить скорость
+	ReadLimit int `json:"read_limit,omitempty"`
+	// Weight — вес цели при подсчете очков (0 означает 1)
+	Weight float64 `json:"weight,omitempty"`
+	// Required — стратегия, провалившая эту цель, не может победить в фазе
+	Required bool `json:"required,omitempty"`
+	// SHA256/Size — ожидаемое содержимое: тело читается целиком и сверяется
+	SHA256 string `json:"sha256,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+	// HeadSHA256/HeadBytes — hex-префикс SHA-256 первых HeadBytes байт тела
+	HeadSHA256 string `json:"head_sha256,omitempty"`
+	HeadBytes  int    `json:"head_bytes,omitempty"`
+	// BodyRegex — регулярное выражение, которое должно найтись в начале тела
+	BodyRegex string `json:"body_regex,omitempty"`
+	// SPKISHA256/Issuer — закрепление сертификата: hex-префикс SHA-256 SPKI листа и подстрока издателя
+	SPKISHA256 string `json:"spki_sha256,omitempty"`
+	Issuer     string `json:"issuer,omitempty"`
+	// IP — закрепленный адрес: подключаемся к нему, минуя резолвер (SNI/Host остаются из URL)
+	IP string `json:"ip,omitempty"`
+	// SSRC — для discord_ip: SSRC активной голосовой сессии (0 — случайный)
+	SSRC uint32 `json:"ssrc,omitempty"`
+	// Payload — для udp: файл запроса (относительно каталога fake)
+	Payload string `json:"payload,omitempty"`
+	// ReplyLen/ReplyPattern — ожидаемый ответ udp: точная длина и/или hex-префикс ("??" — любой байт)
+	ReplyLen     int    `json:"reply_len,omitempty"`
+	ReplyPattern string `json:"reply_pattern,omitempty"`
+}
+
+// EffectiveWeight returns the target weight, defaulting to 1
+func (t Target) EffectiveWeight() float64 {
+	if t.Weight <= 0 {
+		return 1
+	}
+	return t.Weight
 }
 
 // StrategyConfig — это интерфейс, который должна реализовать стратегия NFQWS
@@ -29,17 +106,182 @@ type StrategyConfig interface {
 
 // WorkerResult — результат работы контейнера (JSON output)
 type WorkerResult struct {
-	Success      bool     `json:"success"`
-	Code         int      `json:"code"`
-	Error        string   `json:"error,omitempty"`
-	SuccessCount int      `json:"success_count"`
-	TotalCount   int      `json:"total_count"`
-	Passed       []string `json:"passed,omitempty"`
-	Failed       []string `json:"failed,omitempty"`
+	Success      bool   `json:"success"`
+	Code         int    `json:"code"`
+	Error        string `json:"error,omitempty"`
+	SuccessCount int    `json:"success_count"`
+	TotalCount   int    `json:"total_count"`
+	// Weight — сумма весов пройденных целей, TotalWeight — всех целей группы
+	Weight      float64  `json:"weight,omitempty"`
+	TotalWeight float64  `json:"total_weight,omitempty"`
+	Passed      []string `json:"passed,omitempty"`
+	Failed      []string `json:"failed,omitempty"`
+	Timeouts    int      `json:"timeouts,omitempty"`
+	Aborted     bool     `json:"aborted,omitempty"`
+	// CheckMs — время работы верификатора без запуска nfqws и обмена с оркестратором
+	CheckMs int64 `json:"check_ms,omitempty"`
+	// Targets — результаты по каждой цели (порядок завершения проверок)
+	Targets []TargetResult `json:"targets,omitempty"`
+	// Infra помечает сбой окружения воркера (iptables и т.п.), а не провал стратегии
+	Infra bool `json:"infra,omitempty"`
+	// Worker — воркер, выполнивший оценку (заполняет пул оркестратора)
+	Worker string `json:"worker,omitempty"`
+	// Capabilities заполняется только в ответ на RequestHello
+	Capabilities *WorkerCapabilities `json:"capabilities,omitempty"`
+}
+
+// Типы событий NDJSON-потока, которым воркер отвечает на RequestRun.
+// Поток всегда завершается событием EventResult.
+const (
+	EventNfqwsStarted = "nfqws_started"
+	EventTarget       = "target"
+	EventNfqwsExited  = "nfqws_exited"
+	EventResult       = "result"
+)
+
+// WorkerEvent — одна строка потока воркера
+type WorkerEvent struct {
+	Event   string        `json:"event"`
+	Total   int           `json:"total,omitempty"`  // число целей, в EventNfqwsStarted
+	Weight  float64       `json:"weight,omitempty"` // суммарный вес целей, в EventNfqwsStarted
+	Target  *TargetResult `json:"target,omitempty"`
+	Result  *WorkerResult `json:"result,omitempty"`
+	Message string        `json:"message,omitempty"`
+}
+
+// Классы исхода проверки цели. По ним отличаем вмешательство DPI
+// (RST на рукопожатии, заморозка посреди тела) от честных отказов сервера.
+const (
+	ClassOK               = ""
+	ClassDNS              = "dns"               // имя не разрешилось
+	ClassDial             = "dial"              // TCP/UDP соединение не установлено
+	ClassResetHandshake   = "rst_handshake"     // RST/FIN до завершения TLS рукопожатия
+	ClassHandshakeTimeout = "handshake_timeout" // рукопожатие не завершилось вовремя
+	ClassTLSAlert         = "tls_alert"         // получен TLS alert
+	ClassReset            = "rst"               // RST после рукопожатия, до ответа
+	ClassTimeout          = "timeout"           // нет ответа после рукопожатия
+	ClassStalled          = "stalled"           // передача замерла посреди тела (см. Bytes)
+	ClassStatus           = "http_status"       // ответ с кодом вне 2xx/3xx
+	ClassShort            = "short"             // соединение закрыто до порога байт
+	ClassSlow             = "slow"              // скорость ниже MinThroughput фазы
+	ClassContent          = "content_mismatch"  // размер или хеш тела не совпал
+	ClassBlocked          = "blocked"           // ответила заглушка провайдера (редирект или страница блокировки)
+	ClassMITM             = "tls_mitm"          // сертификат не совпал с закрепленным — подмена TLS
+	ClassCancelled        = "cancelled"         // проверка отменена (гонка или abort)
+	ClassError            = "error"             // прочее
+)
+
+// TargetResult — итог проверки одной цели
+type TargetResult struct {
+	URL       string `json:"url"`
+	Passed    bool   `json:"passed"`
+	LatencyMs int64  `json:"latency_ms"`
+	Bytes     int    `json:"bytes"`
+	Status    int    `json:"status,omitempty"`
+	Class     string `json:"class,omitempty"`
+	// Weight/Required копируются из Target, чтобы очки считались и по частичному результату
+	Weight   float64 `json:"weight,omitempty"`
+	Required bool    `json:"required,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	// Cutoff — передача замерла в окне 16-20KB (режим freeze)
+	Cutoff bool `json:"cutoff,omitempty"`
+
+	// Тайминги фаз запроса (0 — фаза не достигнута или не измерима, например QUIC)
+	ConnectMs int64 `json:"connect_ms,omitempty"`
+	TLSMs     int64 `json:"tls_ms,omitempty"`
+	TTFBMs    int64 `json:"ttfb_ms,omitempty"`
+	// Throughput — устойчивая скорость тела, байт/с (только для целей с ReadLimit)
+	Throughput int64 `json:"throughput,omitempty"`
+}
+
+// ClassCounts returns how many targets ended with each failure class.
+func (r WorkerResult) ClassCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, t := range r.Targets {
+		if !t.Passed {
+			counts[t.Class]++
+		}
+	}
+	return counts
+}
+
+// FillMissing adds the targets an aborted evaluation never reported as cancelled
+// failures, so a partial result cannot dodge must-pass targets it did not reach.
+func (r *WorkerResult) FillMissing(targets []Target) {
+	reported := make(map[string]int, len(r.Targets))
+	for _, t := range r.Targets {
+		reported[t.URL]++
+	}
+	for _, t := range targets {
+		if reported[t.URL] > 0 {
+			reported[t.URL]--
+			continue
+		}
+		r.Targets = append(r.Targets, TargetResult{
+			URL:      t.URL,
+			Class:    ClassCancelled,
+			Weight:   t.EffectiveWeight(),
+			Required: t.Required,
+		})
+		r.Failed = append(r.Failed, t.URL)
+	}
+	if r.TotalCount < len(targets) {
+		r.TotalCount = len(targets)
+	}
+}
+
+// RequiredFailed lists must-pass targets that did not pass
+func (r WorkerResult) RequiredFailed() []string {
+	var out []string
+	for _, t := range r.Targets {
+		if t.Required && !t.Passed {
+			out = append(out, t.URL)
+		}
+	}
+	return out
+}
+
+// WorkerCapabilities описывает окружение воркера, сообщаемое при рукопожатии
+type WorkerCapabilities struct {
+	ProtocolVersion int      `json:"protocol_version"`
+	NfqwsVersion    string   `json:"nfqws_version"`
+	Options         []string `json:"options,omitempty"` // поддерживаемые длинные опции nfqws (без "--")
+	Firewall        string   `json:"firewall"`          // iptables-nft, iptables-legacy, ...
+	IPv6            bool     `json:"ipv6"`
+}
+
+// Supports reports whether nfqws in the worker knows the option (empty list means unknown, assume yes).
+func (c WorkerCapabilities) Supports(option string) bool {
+	if len(c.Options) == 0 {
+		return true
+	}
+	for _, o := range c.Options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// UnsupportedOptions returns options from an nfqws argument string the worker cannot handle.
+func (c WorkerCapabilities) UnsupportedOptions(args string) []string {
+	var out []string
+	for _, tok := range strings.Fields(args) {
+		if !strings.HasPrefix(tok, "--") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimPrefix(tok, "--"), "=")
+		if !c.Supports(name) {
+			out = append(out, name)
+		}
+	}
+	return out
 }
 
 // ScoredStrategy — стратегия с метриками для эволюции
 type ScoredStrategy struct {
+	// ID — идентификатор особи в фазе (<поколение>.<индекс>), на него ссылаются потомки
+	ID         string
 	Config     StrategyConfig
 	RawArgs    string
 	Duration   time.Duration
@@ -52,4 +294,69 @@ type ScoredStrategy struct {
 type ReconReport struct {
 	IPFragWorks bool
 	BadSumWorks bool
+
+	// Детектор заморозки TCP 16-20KB (фаза general без обхода)
+	FreezeCutoff   bool // классическая отсечка обнаружена
+	FreezeInWindow int  // целей, замерших в окне 16-20KB
+	FreezeTotal    int  // целей с пригодным замером
+
+	// TTLDistance — минимальный TTL фейка, при котором он доходит до DPI (0 — не найден)
+	TTLDistance int
+
+	// Fingerprint — матрица отпечатка: как каждая техника меняет исход относительно контроля
+	Fingerprint []FingerprintEntry
+	// QUICDropped — QUIC не проходит ни без обхода, ни с фейком: DPI режет его целиком
+	QUICDropped bool
+
+	// DNSPoisoned — хосты, для которых системный резолвер отдает подмененный ответ
+	DNSPoisoned []string
+
+	// Базовый замер без обхода по целям всех фаз
+	BaselineOpen    int // доступны и так
+	BaselineBlocked int // провал, похожий на DPI
+	BaselineDead    int // мертвы сами по себе
+}
+
+// Техники матрицы отпечатка DPI
+const (
+	TechFake      = "fake"
+	TechMd5Sig    = "md5sig"
+	TechBadSeq    = "badseq"
+	TechBadSum    = "badsum"
+	TechTs        = "ts"
+	TechDatanoack = "datanoack"
+	TechSplitPos1 = "split_pos1"
+	TechSplitSNI  = "split_sni"
+	TechDisorder  = "disorder"
+	TechSeqOvl    = "seqovl"
+	TechWSSize    = "wssize"
+	TechQuicFake  = "quic_fake"
+)
+
+// FingerprintEntry — результат одной техники против контроля (nfqws без десинхронизации)
+type FingerprintEntry struct {
+	Technique string
+	Group     string
+	Args      string
+	Passed    int
+	Total     int
+	Control   int // успехов у контроля на той же группе
+	Err       string
+}
+
+// Effective reports whether the technique changed the outcome for the better
+func (e FingerprintEntry) Effective() bool {
+	return e.Err == "" && e.Passed > e.Control
+}
+
+// Ignored reports that the technique was probed and provably changed nothing.
+// Techniques that were not probed or errored are not ignored, nor are those
+// probed on a group that passed in full without a strategy: there was nothing to bypass.
+func (r ReconReport) Ignored(tech string) bool {
+	for _, e := range r.Fingerprint {
+		if e.Technique == tech {
+			return e.Err == "" && !e.Effective() && e.Control < e.Total
+		}
+	}
+	return false
 }
@@ -0,0 +1,183 @@
+package nfqws
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// setter applies the value of one option to the strategy
+type setter func(s *Strategy, v string) error
+
+func str(field func(s *Strategy) *string) setter {
+	return func(s *Strategy, v string) error {
+		*field(s) = v
+		return nil
+	}
+}
+
+func num(field func(s *Strategy) *int) setter {
+	return func(s *Strategy, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("not a number: %q", v)
+		}
+		*field(s) = n
+		return nil
+	}
+}
+
+// boolean accepts both "--opt" and "--opt=1" forms, "--opt=0" turns it off
+func boolean(field func(s *Strategy) *bool) setter {
+	return func(s *Strategy, v string) error {
+		switch v {
+		case "", "1":
+			*field(s) = true
+		case "0":
+			*field(s) = false
+		default:
+			return fmt.Errorf("expected 0 or 1, got %q", v)
+		}
+		return nil
+	}
+}
+
+// options is the inverse of ToArgs: every option the genome can produce
+var options = map[string]setter{
+	"dpi-desync":              str(func(s *Strategy) *string { return &s.Mode }),
+	"dpi-desync-repeats":      num(func(s *Strategy) *int { return &s.Repeats }),
+	"dpi-desync-any-protocol": boolean(func(s *Strategy) *bool { return &s.AnyProtocol }),
+	"dpi-desync-skip-nosni":   boolean(func(s *Strategy) *bool { return &s.SkipNoSNI }),
+	"dpi-desync-cutoff":       str(func(s *Strategy) *string { return &s.Cutoff }),
+	"dpi-desync-start":        str(func(s *Strategy) *string { return &s.Start }),
+	"dpi-desync-fwmark":       str(func(s *Strategy) *string { return &s.FwMark }),
+
+	"dpi-desync-fooling":               parseFooling,
+	"dpi-desync-badseq-increment":      num(func(s *Strategy) *int { return &s.Fooling.BadSeqIncrement }),
+	"dpi-desync-badack-increment":      num(func(s *Strategy) *int { return &s.Fooling.BadAckIncrement }),
+	"dpi-desync-ts-increment":          num(func(s *Strategy) *int { return &s.Fooling.TsIncrement }),
+	"dpi-desync-fake-tls":              str(func(s *Strategy) *string { return &s.Fake.TLS }),
+	"dpi-desync-fake-quic":             str(func(s *Strategy) *string { return &s.Fake.Quic }),
+	"dpi-desync-fake-http":             str(func(s *Strategy) *string { return &s.Fake.Http }),
+	"dpi-desync-fake-wireguard":        str(func(s *Strategy) *string { return &s.Fake.Wireguard }),
+	"dpi-desync-fake-dht":              str(func(s *Strategy) *string { return &s.Fake.Dht }),
+	"dpi-desync-fake-discord":          str(func(s *Strategy) *string { return &s.Fake.Discord }),
+	"dpi-desync-fake-stun":             str(func(s *Strategy) *string { return &s.Fake.Stun }),
+	"dpi-desync-fake-unknown-udp":      str(func(s *Strategy) *string { return &s.Fake.UnknownUdp }),
+	"dpi-desync-fake-unknown":          str(func(s *Strategy) *string { return &s.Fake.Unknown }),
+	"dpi-desync-fake-syndata":          str(func(s *Strategy) *string { return &s.Fake.SynData }),
+	"dpi-desync-fake-tls-mod":          str(func(s *Strategy) *string { return &s.Fake.TlsMod }),
+	"dpi-desync-fake-tcp-mod":          str(func(s *Strategy) *string { return &s.Fake.TcpMod }),
+	"dpi-desync-split-pos":             str(func(s *Strategy) *string { return &s.Split.Pos }),
+	"dpi-desync-split-seqovl":          num(func(s *Strategy) *int { return &s.Split.SeqOvl }),
+	"dpi-desync-split-seqovl-pattern":  str(func(s *Strategy) *string { return &s.Split.Pattern }),
+	"dpi-desync-fakedsplit-pattern":    str(func(s *Strategy) *string { return &s.Split.FakedPattern }),
+	"dpi-desync-fakedsplit-mod":        str(func(s *Strategy) *string { return &s.Split.FakedMod }),
+	"dpi-desync-hostfakesplit-midhost": str(func(s *Strategy) *string { return &s.Split.HostMid }),
+	"dpi-desync-hostfakesplit-mod":     str(func(s *Strategy) *string { return &s.Split.HostMod }),
+	"dpi-desync-ipfrag-pos-tcp":        num(func(s *Strategy) *int { return &s.Split.IpFragPosTcp }),
+	"dpi-desync-ipfrag-pos-udp":        num(func(s *Strategy) *int { return &s.Split.IpFragPosUdp }),
+	"dpi-desync-udplen-increment":      num(func(s *Strategy) *int { return &s.UdpLen.Increment }),
+	"dpi-desync-udplen-pattern":        str(func(s *Strategy) *string { return &s.UdpLen.Pattern }),
+
+	"dpi-desync-ttl":             num(func(s *Strategy) *int { return &s.TTL.Fixed }),
+	"dpi-desync-ttl6":            num(func(s *Strategy) *int { return &s.TTL.Fixed6 }),
+	"dpi-desync-autottl":         parseAutoTTL,
+	"dpi-desync-autottl6":        num(func(s *Strategy) *int { return &s.TTL.Auto6 }),
+	"dpi-desync-tcp-flags-set":   str(func(s *Strategy) *string { return &s.TcpFlags.Set }),
+	"dpi-desync-tcp-flags-unset": str(func(s *Strategy) *string { return &s.TcpFlags.Unset }),
+
+	"wssize":               parseWSSize,
+	"wssize-cutoff":        str(func(s *Strategy) *string { return &s.WSS.Cutoff }),
+	"wssize-forced-cutoff": boolean(func(s *Strategy) *bool { return &s.WSS.ForcedCutoff }),
+
+	"hostcase":     boolean(func(s *Strategy) *bool { return &s.Tamper.HostCase }),
+	"hostspell":    str(func(s *Strategy) *string { return &s.Tamper.HostSpell }),
+	"hostnospace":  boolean(func(s *Strategy) *bool { return &s.Tamper.HostNoSpace }),
+	"domcase":      boolean(func(s *Strategy) *bool { return &s.Tamper.DomCase }),
+	"methodeol":    boolean(func(s *Strategy) *bool { return &s.Tamper.MethodEol }),
+	"ip-id":        str(func(s *Strategy) *string { return &s.Tamper.IpId }),
+	"synack-split": str(func(s *Strategy) *string { return &s.Tamper.SynAckSplit }),
+
+	"dup":                  num(func(s *Strategy) *int { return &s.Dup.Count }),
+	"dup-replace":          boolean(func(s *Strategy) *bool { return &s.Dup.Replace }),
+	"dup-ttl":              num(func(s *Strategy) *int { return &s.Dup.TTL }),
+	"dup-ttl6":             num(func(s *Strategy) *int { return &s.Dup.TTL6 }),
+	"dup-autottl":          str(func(s *Strategy) *string { return &s.Dup.AutoTTL }),
+	"dup-autottl6":         str(func(s *Strategy) *string { return &s.Dup.AutoTTL6 }),
+	"dup-fooling":          str(func(s *Strategy) *string { return &s.Dup.Fooling }),
+	"dup-ts-increment":     num(func(s *Strategy) *int { return &s.Dup.TsIncrement }),
+	"dup-badseq-increment": num(func(s *Strategy) *int { return &s.Dup.BadSeqIncrement }),
+	"dup-badack-increment": num(func(s *Strategy) *int { return &s.Dup.BadAckIncrement }),
+	"dup-ip-id":            str(func(s *Strategy) *string { return &s.Dup.IpId }),
+	"dup-start":            str(func(s *Strategy) *string { return &s.Dup.Start }),
+	"dup-cutoff":           str(func(s *Strategy) *string { return &s.Dup.Cutoff }),
+	"dup-tcp-flags-set":    str(func(s *Strategy) *string { return &s.Dup.TcpFlagsSet }),
+	"dup-tcp-flags-unset":  str(func(s *Strategy) *string { return &s.Dup.TcpFlagsUnset }),
+
+	"orig-ttl":             num(func(s *Strategy) *int { return &s.Orig.TTL }),
+	"orig-ttl6":            num(func(s *Strategy) *int { return &s.Orig.TTL6 }),
+	"orig-autottl":         str(func(s *Strategy) *string { return &s.Orig.AutoTTL }),
+	"orig-autottl6":        str(func(s *Strategy) *string { return &s.Orig.AutoTTL6 }),
+	"orig-mod-start":       str(func(s *Strategy) *string { return &s.Orig.ModStart }),
+	"orig-mod-cutoff":      str(func(s *Strategy) *string { return &s.Orig.ModCutoff }),
+	"orig-tcp-flags-set":   str(func(s *Strategy) *string { return &s.Orig.TcpFlagsSet }),
+	"orig-tcp-flags-unset": str(func(s *Strategy) *string { return &s.Orig.TcpFlagsUnset }),
+}
+
+func parseFooling(s *Strategy, v string) error {
+	flags := map[string]*bool{
+		"md5sig":    &s.Fooling.Md5Sig,
+		"badsum":    &s.Fooling.BadSum,
+		"badseq":    &s.Fooling.BadSeq,
+		"ts":        &s.Fooling.Ts,
+		"datanoack": &s.Fooling.Datanoack,
+		"hopbyhop":  &s.Fooling.HopByHop,
+		"hopbyhop2": &s.Fooling.HopByHop2,
+	}
+	for _, name := range strings.Split(v, ",") {
+		flag, ok := flags[name]
+		if !ok {
+			return fmt.Errorf("unknown fooling %q", name)
+		}
+		*flag = true
+	}
+	return nil
+}
+
+// parseAutoTTL keeps the plain delta in Auto and anything richer (1:3-64) in AutoStr
+func parseAutoTTL(s *Strategy, v string) error {
+	if n, err := strconv.Atoi(v); err == nil {
+		s.TTL.Auto = n
+		return nil
+	}
+	s.TTL.AutoStr = v
+	return nil
+}
+
+func parseWSSize(s *Strategy, v string) error {
+	s.WSS.Enabled = true
+	s.WSS.Value = v
+	return nil
+}
+
+// ParseArgs turns an nfqws strategy back into a genome, so a deployed winner
+// can seed a new search. Filters and profile options (--filter-*, --hostlist,
+// --new) are not part of the genome and are rejected.
+func ParseArgs(args string) (Strategy, error) {
+	var s Strategy
+	for _, arg := range strings.Fields(args) {
+		name, value, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if !strings.HasPrefix(arg, "--") {
+			return s, fmt.Errorf("unexpected argument %q", arg)
+		}
+		set, ok := options[name]
+		if !ok {
+			return s, fmt.Errorf("option --%s is not part of the strategy genome", name)
+		}
+		if err := set(&s, value); err != nil {
+			return s, fmt.Errorf("--%s: %w", name, err)
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,71 @@
+package nfqws
+
+import "testing"
+
+func TestParseArgsRoundTrip(t *testing.T) {
+	cases := []Strategy{
+		{Mode: "multisplit", Split: SplitOptions{Pos: "1"}, Repeats: 2},
+		{Mode: "multidisorder", Split: SplitOptions{Pos: "midsld", SeqOvl: 336, Pattern: "/fake/tls_clienthello_www_google_com.bin"}},
+		{
+			Mode:    "fake",
+			Repeats: 6,
+			Fooling: FoolingSet{Md5Sig: true, BadSeq: true, Datanoack: true, BadSeqIncrement: -10000},
+			Fake:    FakeOptions{TLS: "/fake/tls_clienthello_vk_com.bin", TlsMod: "rnd,rndsni"},
+			TTL:     TTLOptions{Fixed: 4},
+		},
+		{Mode: "fake", Fake: FakeOptions{Quic: "/fake/quic_initial_www_google_com.bin"}, UdpLen: UdpLenOptions{Increment: 2}, AnyProtocol: true, Cutoff: "n3"},
+		{Mode: "fakedsplit", TTL: TTLOptions{AutoStr: "1:3-64"}, Split: SplitOptions{Pos: "method+2", FakedPattern: "0x00"}},
+		{Mode: "fake", TTL: TTLOptions{Auto: 2}, WSS: WSSOptions{Enabled: true, Value: "1:6", Cutoff: "d2", ForcedCutoff: true}},
+		{Mode: "multisplit", Split: SplitOptions{Pos: "method+2"}, Tamper: TamperOptions{HostCase: true, HostSpell: "hoSt", HostNoSpace: true, DomCase: true, MethodEol: true}},
+		{Mode: "ipfrag1", Repeats: 2, Split: SplitOptions{IpFragPosUdp: 8}},
+	}
+	for _, want := range cases {
+		args := want.ToArgs()
+		got, err := ParseArgs(args)
+		if err != nil {
+			t.Errorf("ParseArgs(%q): %v", args, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseArgs(%q):\n%+v\nwant\n%+v", args, got, want)
+		}
+	}
+}
+
+// TestParseArgsCanonical parses command lines as users deploy them and renders them back
+func TestParseArgsCanonical(t *testing.T) {
+	cases := []struct {
+		args, want string
+	}{
+		{"--dpi-desync=fake --dpi-desync-fooling=badsum,md5sig", "--dpi-desync=fake --dpi-desync-fooling=md5sig,badsum"},
+		{"--dpi-desync=fake --dpi-desync-repeats=1", "--dpi-desync=fake"},
+		{"--hostcase=1 --dpi-desync=multisplit", "--dpi-desync=multisplit --hostcase"},
+		{"--dpi-desync=fake --dpi-desync-skip-nosni", "--dpi-desync=fake --dpi-desync-skip-nosni=1"},
+		{"--wssize=1:6", "--wssize=1:6"},
+	}
+	for _, c := range cases {
+		s, err := ParseArgs(c.args)
+		if err != nil {
+			t.Errorf("ParseArgs(%q): %v", c.args, err)
+			continue
+		}
+		if got := s.ToArgs(); got != c.want {
+			t.Errorf("ParseArgs(%q).ToArgs() = %q, want %q", c.args, got, c.want)
+		}
+	}
+}
+
+func TestParseArgsErrors(t *testing.T) {
+	for _, args := range []string{
+		"--filter-tcp=443 --dpi-desync=fake",
+		"--dpi-desync=fake --new",
+		"fake",
+		"--dpi-desync-repeats=often",
+		"--dpi-desync-fooling=md5sig,magic",
+		"--hostcase=2",
+	} {
+		if s, err := ParseArgs(args); err == nil {
+			t.Errorf("ParseArgs(%q) accepted: %+v", args, s)
+		}
+	}
+}
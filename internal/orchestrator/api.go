@@ -0,0 +1,472 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"prikop/internal/container"
+	"prikop/internal/model"
+	"prikop/internal/verifier"
+)
+
+// apiSubscriberBuffer — событий в очереди SSE-клиента; медленный клиент теряет лишние
+const apiSubscriberBuffer = 256
+
+// Run states reported by the API
+const (
+	RunRunning = "running"
+	RunDone    = "done"
+	RunAborted = "aborted"
+	RunFailed  = "failed"
+)
+
+// RunRequest starts an optimization run. Phases may name only a group to take the
+// built-in phase of that group; set fields override it. No phases means all of them.
+type RunRequest struct {
+	Phases []Phase `json:"phases"`
+}
+
+// Candidate — оценённая стратегия текущего поколения или лучшая в фазе
+type Candidate struct {
+	Args    string  `json:"args"`
+	Score   float64 `json:"score"`
+	Success int     `json:"success"`
+	Total   int     `json:"total"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// RunStatus is the state of the current or last run
+type RunStatus struct {
+	ID       int        `json:"id"`
+	Status   string     `json:"status"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	Phases   []Phase    `json:"phases"`
+
+	Phase     string `json:"phase,omitempty"`
+	Gen       int    `json:"gen"`
+	Gens      int    `json:"gens"`
+	Evaluated int    `json:"evaluated"`
+	// Population — оценки текущего поколения, лучшие первыми
+	Population []Candidate `json:"population"`
+	// Best — лучшая стратегия каждой фазы на данный момент
+	Best map[string]Candidate `json:"best"`
+}
+
+// APIServer runs optimizations on a shared worker pool and reports them over HTTP
+type APIServer struct {
+	cfg  Config
+	caps model.WorkerCapabilities
+	ctx  context.Context
+	// token — bearer-токен, обязательный для всех запросов (пусто — без авторизации)
+	token string
+
+	mu     sync.Mutex
+	run    *RunStatus
+	cancel context.CancelFunc
+	report *model.ReconReport
+	result *Result
+	subs   map[chan Event]struct{}
+}
+
+// Serve starts the worker pool and the HTTP API on addr until interrupted.
+// A non-empty token is required as "Authorization: Bearer <token>" on every request.
+func Serve(cfg Config, addr, token string) int {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	caps, stop := startPool(ctx, cfg)
+	defer stop()
+	resolveLabel(ctx, &cfg)
+
+	s := &APIServer{cfg: cfg, caps: caps, ctx: ctx, token: token, subs: make(map[chan Event]struct{})}
+	srv := &http.Server{Addr: addr, Handler: s.Handler()}
+	go func() {
+		<-ctx.Done()
+		shutdown, done := context.WithTimeout(context.Background(), 5*time.Second)
+		defer done()
+		srv.Shutdown(shutdown)
+	}()
+
+	fmt.Printf(">>> API listening on %s\n", addr)
+	if token == "" && !loopbackAddr(addr) {
+		fmt.Println(">>> WARNING: the API is reachable from the network without a token (-token)")
+	}
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf(">>> API: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// Handler returns the API routes
+func (s *APIServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/phases", s.handlePhases)
+	mux.HandleFunc("POST /api/runs", s.handleStart)
+	mux.HandleFunc("POST /api/runs/stop", s.handleStop)
+	mux.HandleFunc("GET /api/status", s.handleStatus)
+	mux.HandleFunc("GET /api/recon", s.handleRecon)
+	mux.HandleFunc("GET /api/result", s.handleResult)
+	mux.HandleFunc("GET /api/events", s.handleEvents)
+	return s.authorize(mux)
+}
+
+// authorize rejects requests without the bearer token of the server
+func (s *APIServer) authorize(next http.Handler) http.Handler {
+	if s.token == "" {
+		return next
+	}
+	want := []byte("Bearer " + s.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("missing or wrong bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loopbackAddr reports whether a listen address only accepts local connections
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+func (s *APIServer) handlePhases(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, loadPhases(s.cfg))
+}
+
+func (s *APIServer) handleStart(w http.ResponseWriter, r *http.Request) {
+	var req RunRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	phases, err := s.resolvePhases(req.Phases)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.mu.Lock()
+	if s.run != nil && s.run.Status == RunRunning {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, fmt.Errorf("run %d is in progress", s.run.ID))
+		return
+	}
+	id := 1
+	if s.run != nil {
+		id = s.run.ID + 1
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.run = &RunStatus{ID: id, Status: RunRunning, Started: time.Now(), Phases: phases, Best: make(map[string]Candidate)}
+	s.cancel = cancel
+	status := s.snapshot()
+	s.mu.Unlock()
+
+	go s.execute(ctx, id, phases)
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+// resolvePhases completes the requested phases from the built-in ones
+func (s *APIServer) resolvePhases(specs []Phase) ([]Phase, error) {
+	if len(specs) == 0 {
+		return loadPhases(s.cfg), nil
+	}
+
+	phases := make([]Phase, len(specs))
+	for i, spec := range specs {
+		if spec.Group == "" {
+			return nil, fmt.Errorf("phase %d: group is required", i)
+		}
+		p := findPhase(s.cfg, spec.Group).withSpec(spec)
+		if spec.Targets != "" {
+			path, err := s.targetsFile(spec.Targets)
+			if err != nil {
+				return nil, fmt.Errorf("phase %d: %w", i, err)
+			}
+			p.Targets = path
+		}
+		if spec.Resolver != "" {
+			// Custom DoH/DoT endpoints are fetched by workers, only the config may name them
+			switch spec.Resolver {
+			case verifier.ResolverSystem, verifier.ResolverDoH, verifier.ResolverDoT, s.cfg.Resolver:
+			default:
+				return nil, fmt.Errorf("phase %d: resolver %q: only %s, %s, %s or the configured one", i, spec.Resolver, verifier.ResolverSystem, verifier.ResolverDoH, verifier.ResolverDoT)
+			}
+			p.Resolver = spec.Resolver
+		}
+		if _, err := p.loadTargets(); err != nil {
+			return nil, fmt.Errorf("phase %d: %w", i, err)
+		}
+		phases[i] = p
+	}
+	return phases, nil
+}
+
+// targetsFile resolves a requested target file inside the targets directory;
+// clients cannot make the server read files elsewhere
+func (s *APIServer) targetsFile(name string) (string, error) {
+	dir, err := filepath.Abs(s.cfg.TargetsPath)
+	if err != nil {
+		return "", err
+	}
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	path = filepath.Clean(path)
+	if rel, err := filepath.Rel(dir, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("targets %s: outside %s", name, s.cfg.TargetsPath)
+	}
+	return path, nil
+}
+
+// execute performs recon and the phases of one run, like the optimize command
+func (s *APIServer) execute(ctx context.Context, id int, phases []Phase) {
+	s.observe(Event{Type: EventRunStarted, Time: time.Now(), Size: len(phases)})
+
+	finish := func(status string, err error) {
+		s.mu.Lock()
+		if s.run != nil && s.run.ID == id {
+			now := time.Now()
+			s.run.Status = status
+			s.run.Finished = &now
+			if err != nil {
+				s.run.Error = err.Error()
+			}
+		}
+		s.mu.Unlock()
+
+		ev := Event{Type: EventRunDone, Time: time.Now(), Status: status}
+		if err != nil {
+			ev.Error = err.Error()
+		}
+		s.observe(ev)
+	}
+
+	report, ok := runRecon(ctx, s.cfg, s.caps, phases)
+	if !ok {
+		finish(RunAborted, nil)
+		return
+	}
+	s.mu.Lock()
+	s.report = &report
+	s.mu.Unlock()
+
+	bins, err := container.DiscoverBinFiles(s.cfg.FakePath)
+	if err != nil {
+		finish(RunFailed, fmt.Errorf("discover bins: %w", err))
+		return
+	}
+
+	opt := newOptimizer(s.cfg, s.caps)
+	opt.Observers = append(opt.Observers, s.observe)
+	result := executePhases(ctx, opt, phases, bins, report, s.cfg.ResultPath)
+
+	s.mu.Lock()
+	s.result = &result
+	s.mu.Unlock()
+
+	if ctx.Err() != nil {
+		finish(RunAborted, nil)
+		return
+	}
+	finish(RunDone, nil)
+}
+
+// observe updates the run status and forwards the event to SSE subscribers
+func (s *APIServer) observe(ev Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if run := s.run; run != nil {
+		switch ev.Type {
+		case EventPhaseStarted:
+			run.Phase, run.Gen, run.Gens = ev.Phase, 0, ev.Gens
+			run.Population = nil
+		case EventGeneration:
+			run.Gen, run.Gens = ev.Gen, ev.Gens
+			run.Population = run.Population[:0]
+		case EventEvaluation:
+			run.Evaluated++
+			run.Population = append(run.Population, candidate(ev))
+		case EventNewBest:
+			run.Best[ev.Phase] = candidate(ev)
+		}
+	}
+
+	for ch := range s.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+func candidate(ev Event) Candidate {
+	c := Candidate{Args: ev.Args, Score: ev.Score, Error: ev.Error}
+	if ev.Result != nil {
+		c.Success, c.Total = ev.Result.SuccessCount, ev.Result.TotalCount
+	}
+	return c
+}
+
+// snapshot copies the run status; the caller holds s.mu
+func (s *APIServer) snapshot() *RunStatus {
+	if s.run == nil {
+		return nil
+	}
+	st := *s.run
+	st.Population = make([]Candidate, len(s.run.Population))
+	copy(st.Population, s.run.Population)
+	sort.SliceStable(st.Population, func(i, j int) bool { return st.Population[i].Score > st.Population[j].Score })
+	st.Best = make(map[string]Candidate, len(s.run.Best))
+	for k, v := range s.run.Best {
+		st.Best[k] = v
+	}
+	return &st
+}
+
+func (s *APIServer) handleStop(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.run == nil || s.run.Status != RunRunning {
+		writeError(w, http.StatusConflict, errors.New("no run in progress"))
+		return
+	}
+	s.cancel()
+	writeJSON(w, http.StatusAccepted, s.snapshot())
+}
+
+func (s *APIServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	st := s.snapshot()
+	s.mu.Unlock()
+	if st == nil {
+		writeError(w, http.StatusNotFound, errors.New("no run yet"))
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+func (s *APIServer) handleRecon(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	report := s.report
+	s.mu.Unlock()
+	if report == nil {
+		writeError(w, http.StatusNotFound, errors.New("no recon yet"))
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleResult returns the winners of the last run, or of the result file before the first one
+func (s *APIServer) handleResult(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	result := s.result
+	s.mu.Unlock()
+	if result == nil {
+		res, err := LoadResult(s.cfg.ResultPath)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		result = &res
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" || format == FormatJSON {
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+	out, err := result.Render(format)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, out)
+}
+
+// handleEvents streams optimizer events as server-sent events
+func (s *APIServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+
+	ch := make(chan Event, apiSubscriberBuffer)
+	s.mu.Lock()
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subs, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// Comments keep proxies from closing an idle stream between generations
+	keepalive := time.NewTicker(15 * time.Second)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.ctx.Done():
+			return
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case ev := <-ch:
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,108 @@
+package orchestrator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	cases := []struct {
+		token  string
+		header string
+		want   int
+	}{
+		{"", "", http.StatusNoContent},
+		{"secret", "Bearer secret", http.StatusNoContent},
+		{"secret", "", http.StatusUnauthorized},
+		{"secret", "Bearer wrong", http.StatusUnauthorized},
+		{"secret", "Bearer secret2", http.StatusUnauthorized},
+		{"secret", "secret", http.StatusUnauthorized},
+		{"secret", "Basic c2VjcmV0", http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		s := &APIServer{token: c.token}
+		req := httptest.NewRequest(http.MethodGet, "/api/status", nil)
+		if c.header != "" {
+			req.Header.Set("Authorization", c.header)
+		}
+		rec := httptest.NewRecorder()
+		s.authorize(ok).ServeHTTP(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("token %q, header %q: status %d, want %d", c.token, c.header, rec.Code, c.want)
+		}
+	}
+}
+
+func TestTargetsFile(t *testing.T) {
+	dir := t.TempDir()
+	s := &APIServer{cfg: Config{TargetsPath: dir}}
+	cases := []struct {
+		name string
+		want string // "" — rejected
+	}{
+		{"general.json", filepath.Join(dir, "general.json")},
+		{"custom/youtube.json", filepath.Join(dir, "custom", "youtube.json")},
+		{"./custom/../general.json", filepath.Join(dir, "general.json")},
+		{filepath.Join(dir, "http.json"), filepath.Join(dir, "http.json")},
+		{"../secret.json", ""},
+		{"custom/../../secret.json", ""},
+		{"/etc/passwd", ""},
+		{"..", ""},
+	}
+	for _, c := range cases {
+		got, err := s.targetsFile(c.name)
+		switch {
+		case c.want == "" && err == nil:
+			t.Errorf("targetsFile(%q) = %q, want rejected", c.name, got)
+		case c.want != "" && err != nil:
+			t.Errorf("targetsFile(%q): %v", c.name, err)
+		case got != c.want:
+			t.Errorf("targetsFile(%q) = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestLoopbackAddr(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1:8080": true,
+		"localhost:8080": true,
+		"[::1]:8080":     true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"10.0.0.2:8080":  false,
+		"8080":           false,
+	}
+	for addr, want := range cases {
+		if got := loopbackAddr(addr); got != want {
+			t.Errorf("loopbackAddr(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+// TestResolvePhasesResolver checks that clients cannot point workers at their own resolvers
+func TestResolvePhasesResolver(t *testing.T) {
+	s := &APIServer{cfg: Config{TargetsPath: "../../targets", Resolver: "dot:9.9.9.9:853"}}
+	cases := []struct {
+		resolver string
+		ok       bool
+	}{
+		{"", true},
+		{"system", true},
+		{"doh", true},
+		{"dot", true},
+		{"dot:9.9.9.9:853", true},
+		{"doh:https://attacker.example/dns-query", false},
+		{"dot:203.0.113.1:853", false},
+	}
+	for _, c := range cases {
+		_, err := s.resolvePhases([]Phase{{Group: "http", Resolver: c.resolver}})
+		if (err == nil) != c.ok {
+			t.Errorf("resolver %q: err %v, want ok=%v", c.resolver, err, c.ok)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"prikop/internal/model"
+	"prikop/internal/verifier"
+)
+
+// AdHocGens — поколений у фазы, собранной для группы без встроенной фазы
+const AdHocGens = 5
+
+// findPhase returns the phase of a target group, or an ad-hoc phase for groups without one
+func findPhase(cfg Config, group string) Phase {
+	for _, p := range loadPhases(cfg) {
+		if p.Group == group {
+			return p
+		}
+	}
+	return Phase{Name: group, Group: group, Gens: AdHocGens, Targets: verifier.GroupFile(cfg.TargetsPath, group), Resolver: cfg.Resolver}
+}
+
+// evaluate runs one strategy against the phase targets on the pool
+func evaluate(ctx context.Context, args string, phase Phase) (model.WorkerResult, error) {
+	g, err := phase.loadTargets()
+	if err != nil {
+		return model.WorkerResult{}, err
+	}
+	return pool.Exec(ctx, model.WorkerRequest{
+		StrategyArgs:  args,
+		TargetGroup:   phase.Group,
+		MinThroughput: phase.MinThroughput,
+		Targets:       g.Targets,
+		Resolver:      phase.Resolver,
+	})
+}
+
+// Minimize drops arguments of a strategy one by one while it keeps passing as many
+// targets as the full strategy, and prints the shortest working command line.
+func Minimize(cfg Config, args, group string, runs int) int {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	_, stop := startPool(ctx, cfg)
+	defer stop()
+
+	phase := findPhase(cfg, group)
+
+	// The weakest of several runs, a lucky single pass must not keep an argument
+	score := func(args string) (int, error) {
+		worst := -1
+		for i := 0; i < runs; i++ {
+			res, err := evaluate(ctx, args, phase)
+			if err != nil {
+				return 0, err
+			}
+			if len(res.RequiredFailed()) > 0 {
+				return 0, nil
+			}
+			if worst < 0 || res.SuccessCount < worst {
+				worst = res.SuccessCount
+			}
+		}
+		return worst, nil
+	}
+
+	tokens := strings.Fields(args)
+	need, err := score(args)
+	if err != nil {
+		fmt.Printf(">>> Minimize: %v\n", err)
+		return 1
+	}
+	fmt.Printf(">>> MINIMIZE on %s: full strategy passes %d targets\n", phase.Group, need)
+	if need == 0 {
+		fmt.Println(">>> Nothing to minimize: the strategy does not work")
+		return 1
+	}
+
+	for i := 0; i < len(tokens); {
+		if ctx.Err() != nil {
+			return 1
+		}
+		candidate := slices.Delete(slices.Clone(tokens), i, i+1)
+		got, err := score(strings.Join(candidate, " "))
+		if err != nil {
+			fmt.Printf(">>> Minimize: %v\n", err)
+			return 1
+		}
+		if got >= need {
+			fmt.Printf("    [-] %s not needed (%d)\n", tokens[i], got)
+			tokens = candidate
+			continue
+		}
+		fmt.Printf("    [+] %s required (%d without it)\n", tokens[i], got)
+		i++
+	}
+
+	fmt.Printf(">>> MINIMAL: %s\n", strings.Join(tokens, " "))
+	return 0
+}
+
+// Bench measures how fast the pool evaluates: n no-desync evaluations on the group
+func Bench(cfg Config, group string, n int) int {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	_, stop := startPool(ctx, cfg)
+	defer stop()
+
+	if n <= 0 {
+		n = pool.Size() * 2
+	}
+	phase := findPhase(cfg, group)
+	fmt.Printf(">>> BENCH: %d evaluations on %s with %d workers\n", n, phase.Group, pool.Size())
+	pool.TakeStats()
+
+	latencies := make([]time.Duration, n)
+	errs := 0
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			t := time.Now()
+			_, err := evaluate(ctx, "", phase)
+			latencies[i] = time.Since(t)
+			if err != nil {
+				mu.Lock()
+				errs++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	slices.Sort(latencies)
+	stats := pool.TakeStats()
+	fmt.Printf(">>> %d evaluations in %s: %.2f evals/s\n", n, elapsed.Round(time.Millisecond), float64(n)/elapsed.Seconds())
+	fmt.Printf(">>> latency p50 %s, p95 %s, max %s\n",
+		latencies[n/2].Round(time.Millisecond), latencies[n*95/100].Round(time.Millisecond), latencies[n-1].Round(time.Millisecond))
+	fmt.Printf(">>> errors %d, infra errors %.0f%%, check timeouts %.0f%%\n", errs, stats.InfraRate()*100, stats.TimeoutRate()*100)
+	return 0
+}
+
+// Export prints the last optimize result in the given format
+func Export(cfg Config, format string) int {
+	res, err := LoadResult(cfg.ResultPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 1
+	}
+	out, err := res.Render(format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 1
+	}
+	fmt.Println(out)
+	return 0
+}
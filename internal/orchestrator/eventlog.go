@@ -0,0 +1,61 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// evaluationRecord — строка журнала оценок: событие evaluation и метка провайдера,
+// чтобы журналы разных сетей можно было сложить в один набор данных
+type evaluationRecord struct {
+	Event
+	Label string `json:"label,omitempty"`
+}
+
+// eventLog appends every evaluation to a JSONL file
+type eventLog struct {
+	mu    sync.Mutex
+	f     *os.File
+	enc   *json.Encoder
+	label string
+}
+
+var (
+	eventLogsMu sync.Mutex
+	eventLogs   = make(map[string]*eventLog)
+)
+
+// openEventLog returns the log of the path; optimizers of one process share it
+func openEventLog(path, label string) (*eventLog, error) {
+	eventLogsMu.Lock()
+	defer eventLogsMu.Unlock()
+	if l, ok := eventLogs[path]; ok {
+		return l, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	l := &eventLog{f: f, enc: json.NewEncoder(f), label: label}
+	eventLogs[path] = l
+	return l, nil
+}
+
+// observe is the optimizer observer of the log
+func (l *eventLog) observe(ev Event) {
+	if ev.Type != EventEvaluation {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(evaluationRecord{Event: ev, Label: l.label}); err != nil {
+		fmt.Printf(">>> Event log: %v\n", err)
+	}
+}
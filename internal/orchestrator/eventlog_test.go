@@ -0,0 +1,98 @@
+package orchestrator
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"prikop/internal/model"
+)
+
+func TestEventLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "events.jsonl")
+	l, err := openEventLog(path, "AS12389 PJSC Rostelecom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		eventLogsMu.Lock()
+		delete(eventLogs, path)
+		eventLogsMu.Unlock()
+		l.f.Close()
+	}()
+
+	// Optimizers of one process share the log of a path
+	if again, err := openEventLog(path, "other"); err != nil || again != l {
+		t.Fatalf("second open: %p %v, want the shared log %p", again, err, l)
+	}
+
+	// Only evaluations are logged; they arrive from many goroutines at once
+	const evaluations = 20
+	for _, typ := range []string{EventRunStarted, EventPhaseStarted, EventGeneration, EventNewBest, EventPhaseDone, EventRunDone} {
+		l.observe(Event{Type: typ, Phase: "GENERAL"})
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < evaluations; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l.observe(Event{
+				Type:     EventEvaluation,
+				Phase:    "GENERAL",
+				Group:    "general",
+				Gen:      1,
+				ID:       fmt.Sprintf("g1-%d", i),
+				Parents:  []string{"g0-1", "g0-2"},
+				Operator: "crossover",
+				Worker:   "prikop-worker-0",
+				Args:     "--dpi-desync=fake",
+				Score:    50,
+				Result: &model.WorkerResult{
+					SuccessCount: 1,
+					TotalCount:   2,
+					Targets: []model.TargetResult{
+						{URL: "https://a", Passed: true},
+						{URL: "https://b", Class: model.ClassTimeout},
+					},
+				},
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var rec evaluationRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		switch {
+		case rec.Type != EventEvaluation:
+			t.Errorf("%s event logged", rec.Type)
+		case rec.Label != "AS12389 PJSC Rostelecom":
+			t.Errorf("%s: label %q", rec.ID, rec.Label)
+		case len(rec.Parents) != 2 || rec.Operator != "crossover" || rec.Worker == "":
+			t.Errorf("%s: lineage lost: %+v", rec.ID, rec)
+		case rec.Result == nil || len(rec.Result.Targets) != 2 || rec.Result.Targets[1].Class != model.ClassTimeout:
+			t.Errorf("%s: per-target results lost: %+v", rec.ID, rec.Result)
+		}
+		seen[rec.ID] = true
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != evaluations {
+		t.Errorf("%d evaluations logged, want %d", len(seen), evaluations)
+	}
+}
@@ -0,0 +1,273 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"prikop/internal/container"
+	"prikop/internal/model"
+	"prikop/internal/nfqws"
+)
+
+// MonitorOptions configures the monitoring daemon
+type MonitorOptions struct {
+	Interval time.Duration
+	// Runs — прогонов на фазу при каждой проверке
+	Runs int
+	// HistoryPath — JSONL-файл истории проверок (пусто — не сохранять)
+	HistoryPath string
+	// Gens — поколений при повторной оптимизации (0 — как у фазы)
+	Gens int
+	// Webhook получает POST с MonitorEvent в JSON
+	Webhook string
+	// Hook — команда sh, MonitorEvent приходит в stdin
+	Hook string
+	// Once — одна проверка и выход (для cron)
+	Once bool
+}
+
+// MonitorRecord — одна проверка фазы (строка JSONL истории)
+type MonitorRecord struct {
+	Time  time.Time `json:"time"`
+	Phase string    `json:"phase"`
+	Group string    `json:"group"`
+	Args  string    `json:"args"`
+	Rate  int       `json:"rate"`
+	Runs  int       `json:"runs"`
+	// Failing — цели, не прошедшие хотя бы один прогон
+	Failing []string `json:"failing,omitempty"`
+}
+
+// Monitor event types
+const (
+	EventDegraded        = "degraded"
+	EventReoptimized     = "reoptimized"
+	EventReoptimizeError = "reoptimize_failed"
+)
+
+// MonitorEvent is sent to the webhook and the hook command
+type MonitorEvent struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`
+	Phase   string    `json:"phase"`
+	Group   string    `json:"group"`
+	Rate    int       `json:"rate"`
+	OldArgs string    `json:"old_args"`
+	NewArgs string    `json:"new_args,omitempty"`
+	// Config — полная конфигурация nfqws после замены победителя
+	Config string `json:"config,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Monitor periodically re-verifies the deployed phase winners from the result file.
+// A phase passing less than TargetSuccessRate is optimized again, seeded with
+// its old winner; the new winner replaces it in the result file.
+func Monitor(cfg Config, opts MonitorOptions) int {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	caps, stop := startPool(ctx, cfg)
+	defer stop()
+	resolveLabel(ctx, &cfg)
+
+	m := &monitor{cfg: cfg, opts: opts, caps: caps}
+	fmt.Printf(">>> MONITOR: %s every %s, %d runs per phase\n", cfg.ResultPath, opts.Interval, opts.Runs)
+
+	for {
+		ok := m.check(ctx)
+		if ctx.Err() != nil {
+			fmt.Println("\n>>> Monitor stopped.")
+			return 0
+		}
+		if opts.Once {
+			if !ok {
+				return 1
+			}
+			return 0
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Println("\n>>> Monitor stopped.")
+			return 0
+		case <-time.After(opts.Interval):
+		}
+	}
+}
+
+type monitor struct {
+	cfg  Config
+	opts MonitorOptions
+	caps model.WorkerCapabilities
+
+	// report is collected on the first degradation of a check: the DPI has changed since optimize
+	report *model.ReconReport
+	bins   []string
+	// optimize is created on the first degradation and reused by later checks
+	optimize *Optimizer
+}
+
+// check verifies every phase once; false when a phase is degraded and could not be repaired
+func (m *monitor) check(ctx context.Context) bool {
+	res, err := LoadResult(m.cfg.ResultPath)
+	if err != nil {
+		fmt.Printf(">>> Monitor: %v\n", err)
+		return false
+	}
+
+	fmt.Printf("\n>>> CHECK %s\n", time.Now().Format(time.DateTime))
+	m.report = nil
+	healthy := true
+	for i, pr := range res.Phases {
+		phase := findPhase(m.cfg, pr.Group)
+		stats, err := verifyGroup(ctx, pr.Args, phase, m.opts.Runs)
+		if ctx.Err() != nil {
+			return false
+		}
+		if err != nil {
+			fmt.Printf(">>> %s: %v\n", pr.Name, err)
+			healthy = false
+			continue
+		}
+
+		rec := MonitorRecord{Time: time.Now(), Phase: pr.Name, Group: pr.Group, Args: pr.Args, Rate: stats.Rate(), Runs: stats.Runs}
+		for _, t := range stats.Targets {
+			if t.Passed < t.Runs {
+				rec.Failing = append(rec.Failing, t.URL)
+			}
+		}
+		m.record(rec)
+
+		if rec.Rate >= model.TargetSuccessRate {
+			fmt.Printf(">>> %s: %d%% OK\n", pr.Name, rec.Rate)
+			continue
+		}
+
+		fmt.Printf(">>> %s: %d%%, below %d%% — DEGRADED\n", pr.Name, rec.Rate, model.TargetSuccessRate)
+		printGroupStats(stats, model.TargetSuccessRate)
+		m.notify(MonitorEvent{Time: time.Now(), Event: EventDegraded, Phase: pr.Name, Group: pr.Group, Rate: rec.Rate, OldArgs: pr.Args})
+
+		next, err := m.reoptimize(ctx, phase, pr)
+		if ctx.Err() != nil {
+			return false
+		}
+		if err != nil {
+			fmt.Printf(">>> %s: %v\n", pr.Name, err)
+			m.notify(MonitorEvent{Time: time.Now(), Event: EventReoptimizeError, Phase: pr.Name, Group: pr.Group, Rate: rec.Rate, OldArgs: pr.Args, Error: err.Error()})
+			healthy = false
+			continue
+		}
+
+		res.Phases[i] = next
+		res.Time = time.Now()
+		if err := SaveResult(m.cfg.ResultPath, res); err != nil {
+			fmt.Printf(">>> Result: %v\n", err)
+		}
+		config, _ := res.Render(FormatNfqws)
+		fmt.Printf(">>> %s: new winner %s\n", pr.Name, next.Args)
+		m.notify(MonitorEvent{Time: time.Now(), Event: EventReoptimized, Phase: pr.Name, Group: pr.Group, Rate: rec.Rate, OldArgs: pr.Args, NewArgs: next.Args, Config: config})
+	}
+	return healthy
+}
+
+// reoptimize runs the phase again, starting from the old winner and its mutants
+func (m *monitor) reoptimize(ctx context.Context, phase Phase, old PhaseResult) (PhaseResult, error) {
+	if m.report == nil {
+		report, ok := runRecon(ctx, m.cfg, m.caps, []Phase{phase})
+		if !ok {
+			return old, ctx.Err()
+		}
+		bins, err := container.DiscoverBinFiles(m.cfg.FakePath)
+		if err != nil {
+			return old, fmt.Errorf("discover bins: %w", err)
+		}
+		m.report, m.bins = &report, bins
+	}
+	// Оптимизатор живет весь мониторинг: он держит журнал событий и масштабатор пула
+	if m.optimize == nil {
+		m.optimize = newOptimizer(m.cfg, m.caps)
+	}
+
+	if seed, err := nfqws.ParseArgs(old.Args); err == nil {
+		phase.Seeds = []nfqws.Strategy{seed}
+	} else {
+		fmt.Printf(">>> Old winner cannot seed the search: %v\n", err)
+	}
+	if m.opts.Gens > 0 {
+		phase.Gens = m.opts.Gens
+	}
+
+	fmt.Printf("\n>>> RE-OPTIMIZING %s\n", phase.Name)
+	best, skipped := m.optimize.RunPhase(ctx, phase, m.bins, *m.report)
+	if skipped {
+		return old, fmt.Errorf("targets are reachable without bypass now, winner kept")
+	}
+	if best == nil {
+		return old, fmt.Errorf("no working strategy found")
+	}
+	next := old
+	next.Args = best.Config.ToArgs()
+	next.Success = best.Result.SuccessCount
+	next.Total = best.Result.TotalCount
+	return next, nil
+}
+
+// record appends the check to the monitor history
+func (m *monitor) record(rec MonitorRecord) {
+	path := m.opts.HistoryPath
+	if path == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		fmt.Printf(">>> Monitor history: %v\n", err)
+		return
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		fmt.Printf(">>> Monitor history: %v\n", err)
+		return
+	}
+	defer f.Close()
+	if err := json.NewEncoder(f).Encode(rec); err != nil {
+		fmt.Printf(">>> Monitor history: %v\n", err)
+	}
+}
+
+// notify delivers the event to the webhook and the hook command, failures are only logged
+func (m *monitor) notify(ev MonitorEvent) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+
+	if m.opts.Webhook != "" {
+		client := http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Post(m.opts.Webhook, "application/json", bytes.NewReader(data))
+		if err != nil {
+			fmt.Printf(">>> Webhook: %v\n", err)
+		} else {
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				fmt.Printf(">>> Webhook: %s\n", resp.Status)
+			}
+		}
+	}
+
+	if m.opts.Hook != "" {
+		cmd := exec.Command("sh", "-c", m.opts.Hook)
+		cmd.Stdin = bytes.NewReader(data)
+		cmd.Env = append(os.Environ(), "PRIKOP_EVENT="+ev.Event, "PRIKOP_PHASE="+ev.Phase, "PRIKOP_CONFIG="+ev.Config)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			fmt.Printf(">>> Hook: %v: %s\n", err, out)
+		}
+	}
+}
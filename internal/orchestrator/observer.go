@@ -0,0 +1,63 @@
+package orchestrator
+
+import (
+	"time"
+
+	"prikop/internal/model"
+)
+
+// Типы событий оптимизатора
+const (
+	EventRunStarted   = "run_started"
+	EventPhaseStarted = "phase_started"
+	EventGeneration   = "generation"
+	EventEvaluation   = "evaluation"
+	EventNewBest      = "new_best"
+	EventPhaseDone    = "phase_done"
+	EventRunDone      = "run_done"
+)
+
+// Event describes optimizer progress for observers (API, UI, logs).
+// Only the fields of its type are set.
+type Event struct {
+	Type  string    `json:"type"`
+	Time  time.Time `json:"time"`
+	Phase string    `json:"phase,omitempty"`
+	Group string    `json:"group,omitempty"`
+
+	// Gen/Gens — номер и число поколений, Size — размер популяции
+	Gen  int `json:"gen"`
+	Gens int `json:"gens,omitempty"`
+	Size int `json:"size,omitempty"`
+
+	// Происхождение оценённой особи: ID, родители из прошлого поколения и оператор
+	ID       string   `json:"id,omitempty"`
+	Parents  []string `json:"parents,omitempty"`
+	Operator string   `json:"operator,omitempty"`
+	Worker   string   `json:"worker,omitempty"`
+
+	// Оценка стратегии (evaluation, new_best, phase_done)
+	Args       string              `json:"args,omitempty"`
+	Score      float64             `json:"score,omitempty"`
+	DurationMs int64               `json:"duration_ms,omitempty"`
+	Result     *model.WorkerResult `json:"result,omitempty"`
+
+	// Status of phase_done: winner, skipped, failed; of run_done: done, aborted
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Observer receives optimizer events. It is called from evaluation goroutines
+// concurrently and must not block.
+type Observer func(Event)
+
+// emit stamps the event and passes it to every observer
+func (o *Optimizer) emit(ev Event) {
+	if len(o.Observers) == 0 {
+		return
+	}
+	ev.Time = time.Now()
+	for _, observe := range o.Observers {
+		observe(ev)
+	}
+}
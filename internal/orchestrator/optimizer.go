@@ -3,7 +3,9 @@ package orchestrator
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,38 +14,93 @@ import (
 	"prikop/internal/galaxy"
 	"prikop/internal/model"
 	"prikop/internal/nfqws"
+	"prikop/internal/recon"
+	"prikop/internal/verifier"
 )
 
+// SeedMutants — сколько мутантов каждой затравки добавляется в нулевое поколение
+const SeedMutants = 10
+
 // Optimizer handles the evolutionary process for a specific phase
 type Optimizer struct {
 	Pool *container.WorkerPool
+	// Scaler is optional: when set, pool concurrency adapts after each generation
+	Scaler *Scaler
+	// Caps describes the workers; strategies using options they lack are not sent
+	Caps model.WorkerCapabilities
+	// AbortHopeless stops an evaluation as soon as it can no longer reach the current best
+	AbortHopeless bool
+	// Racing lets workers cancel checks once the elite threshold of the previous generation is unreachable
+	Racing bool
+	// Observers receive progress events of every phase
+	Observers []Observer
 }
 
 func NewOptimizer(pool *container.WorkerPool) *Optimizer {
 	return &Optimizer{Pool: pool}
 }
 
-func (o *Optimizer) RunPhase(ctx context.Context, group string, bins []string, maxGens int, report model.ReconReport) *model.ScoredStrategy {
-	population := galaxy.GenerateZeroGeneration(bins, report)
+// RunPhase evolves strategies for the phase. skipped is true when the baseline
+// shows there is nothing to bypass: every live target is reachable as is.
+func (o *Optimizer) RunPhase(ctx context.Context, phase Phase, bins []string, report model.ReconReport) (best *model.ScoredStrategy, skipped bool) {
+	maxGens := phase.Gens
+
+	g, err := phase.loadTargets()
+	if err != nil {
+		fmt.Printf(">>> Targets: %v\n", err)
+		return nil, false
+	}
+	phase.targets = excludePoisoned(g.Targets, phase.Resolver, report.DNSPoisoned)
+	if len(phase.targets) == 0 {
+		fmt.Println(">>> Targets: every target is DNS-poisoned, phase skipped")
+		return nil, false
+	}
+	fmt.Printf(">>> Targets: %s, %d checks from %s\n", g.Name, len(phase.targets), phase.Targets)
+	o.emit(Event{Type: EventPhaseStarted, Phase: phase.Name, Group: phase.Group, Gens: maxGens, Size: len(phase.targets)})
+
+	if o.baseline(ctx, &phase) {
+		return nil, true
+	}
+	if len(phase.targets) == 0 {
+		fmt.Println(">>> No live targets left")
+		return nil, false
+	}
+	if ctx.Err() != nil {
+		return nil, false
+	}
+
+	population := seedPopulation(phase.Seeds, bins, report)
+	for _, s := range galaxy.GenerateZeroGeneration(bins, report, strings.Contains(phase.Filters, "--filter-tcp")) {
+		population = append(population, evolution.Individual{Strategy: s, Operator: evolution.OpGalaxy})
+	}
 	var globalBest *model.ScoredStrategy
+	minWeight := 0.0
 
 	for gen := 0; gen < maxGens; gen++ {
 		// CHECKPOINT: Check before generation
 		select {
 		case <-ctx.Done():
-			return nil
+			return nil, false
 		default:
 		}
 
 		fmt.Printf(">>> GEN %d/%d (%d strategies)\n", gen, maxGens, len(population))
+		o.emit(Event{Type: EventGeneration, Phase: phase.Name, Group: phase.Group, Gen: gen, Gens: maxGens, Size: len(population)})
 
-		results := o.executeBatch(ctx, population, group)
+		var best *model.WorkerResult
+		if globalBest != nil {
+			best = &globalBest.Result
+		}
+		results := o.executeBatch(ctx, population, phase, gen, best, minWeight)
 
 		// If context died during executeBatch
 		if ctx.Err() != nil {
-			return nil
+			return nil, false
 		}
 
+		o.logPoolStats()
+		logFailureClasses(results)
+
 		sort.Slice(results, func(i, j int) bool {
 			return evolution.CalculateScore(results[i].Result, results[i].Complexity) >
 				evolution.CalculateScore(results[j].Result, results[j].Complexity)
@@ -55,8 +112,10 @@ func (o *Optimizer) RunPhase(ctx context.Context, group string, bins []string, m
 
 			if globalBest == nil || score > evolution.CalculateScore(globalBest.Result, globalBest.Complexity) {
 				globalBest = &bestGen
-				fmt.Printf(">>> NEW BEST: %s (Success: %d/%d)\n", globalBest.Config.ToArgs(), globalBest.Result.SuccessCount, globalBest.Result.TotalCount)
+				fmt.Printf(">>> NEW BEST: %s (Success: %d/%d, score %.1f)\n", globalBest.Config.ToArgs(), globalBest.Result.SuccessCount, globalBest.Result.TotalCount, score)
 				o.logResultDetails(globalBest)
+				o.emit(Event{Type: EventNewBest, Phase: phase.Name, Group: phase.Group, Gen: gen, Gens: maxGens,
+					ID: globalBest.ID, Args: globalBest.RawArgs, Score: score, Result: &globalBest.Result})
 			}
 		}
 
@@ -68,45 +127,217 @@ func (o *Optimizer) RunPhase(ctx context.Context, group string, bins []string, m
 			}
 		}
 
-		population = evolution.Evolve(results, bins)
+		if o.Racing {
+			minWeight = eliteThreshold(results)
+			if minWeight > 0 {
+				fmt.Printf(">>> Racing: next generation needs a passed weight of at least %.1f\n", minWeight)
+			}
+		}
+
+		population = evolution.Evolve(results, bins, report)
 		if len(population) == 0 {
 			break
 		}
 	}
 
-	return globalBest
+	if globalBest != nil {
+		if missed := globalBest.Result.RequiredFailed(); len(missed) > 0 {
+			fmt.Printf(">>> Best strategy misses must-pass targets %v, not accepted\n", missed)
+			return nil, false
+		}
+	}
+
+	return globalBest, false
+}
+
+// seedPopulation returns the seeds followed by SeedMutants mutants of each:
+// a strategy that broke usually needs a small change, not a new search.
+// Seeds open generation 0, so their mutants name them as parents.
+func seedPopulation(seeds []nfqws.Strategy, bins []string, report model.ReconReport) []evolution.Individual {
+	mutator := evolution.NewMutator(bins, report)
+	var population []evolution.Individual
+	for _, seed := range seeds {
+		population = append(population, evolution.Individual{Strategy: seed, Operator: evolution.OpSeed})
+	}
+	for i, seed := range seeds {
+		parent := []string{evolution.IndividualID(0, i)}
+		for k := 0; k < SeedMutants; k++ {
+			child := seed
+			mutator.Mutate(&child)
+			population = append(population, evolution.Individual{Strategy: child, Parents: parent, Operator: evolution.OpMutation})
+		}
+	}
+	return population
+}
+
+// baseline checks the phase targets without NFQUEUE and nfqws. Targets dead in the
+// check and again in a re-check are dropped from the phase; returns true when the
+// rest is reachable without bypass.
+func (o *Optimizer) baseline(ctx context.Context, phase *Phase) bool {
+	res, err := o.baselineRun(ctx, *phase, phase.targets)
+	if err != nil {
+		fmt.Printf(">>> Baseline: %v\n", err)
+		return false
+	}
+
+	// Один сбой DNS или соединения — не повод выкинуть цель на всю фазу
+	var suspects []model.Target
+	for _, t := range phase.targets {
+		for _, r := range res.Targets {
+			if r.URL == t.URL && !r.Passed && verifier.DeadClass(r.Class) {
+				suspects = append(suspects, t)
+				break
+			}
+		}
+	}
+	if len(suspects) > 0 {
+		fmt.Printf(">>> Baseline: re-checking %d dead targets\n", len(suspects))
+		again, err := o.baselineRun(ctx, *phase, suspects)
+		if err != nil {
+			fmt.Printf(">>> Baseline re-check: %v, keeping the targets\n", err)
+		}
+		recheck := make(map[string]model.TargetResult, len(again.Targets))
+		for _, r := range again.Targets {
+			recheck[r.URL] = r
+		}
+		for i, r := range res.Targets {
+			if verifier.DeadClass(r.Class) && !r.Passed {
+				// Без результата повторной проверки цель остается в фазе
+				r2, ok := recheck[r.URL]
+				if !ok {
+					r2 = r
+					r2.Class = model.ClassCancelled
+				}
+				res.Targets[i] = r2
+			}
+		}
+	}
+
+	open := 0
+	dead := make(map[string]bool)
+	for _, t := range res.Targets {
+		if t.Passed {
+			open++
+		} else if verifier.DeadClass(t.Class) {
+			dead[t.URL] = true
+		}
+	}
+	fmt.Printf(">>> Baseline without bypass: %d open, %d blocked, %d dead\n", open, len(res.Targets)-open-len(dead), len(dead))
+	recon.PrintBaseline(res.Targets)
+
+	if len(dead) > 0 {
+		live := phase.targets[:0:0]
+		for _, t := range phase.targets {
+			if !dead[t.URL] {
+				live = append(live, t)
+			}
+		}
+		phase.targets = live
+		fmt.Printf(">>> Dropped %d dead targets, %d left\n", len(dead), len(live))
+	}
+
+	if len(phase.targets) > 0 && open == len(phase.targets) {
+		fmt.Println(">>> All targets reachable without bypass, phase skipped")
+		return true
+	}
+	return false
+}
+
+// baselineRun checks targets of the phase on a clean network
+func (o *Optimizer) baselineRun(ctx context.Context, phase Phase, targets []model.Target) (model.WorkerResult, error) {
+	return o.Pool.Exec(ctx, model.WorkerRequest{
+		TargetGroup:   phase.Group,
+		Targets:       targets,
+		MinThroughput: phase.MinThroughput,
+		Resolver:      phase.Resolver,
+		Baseline:      true,
+	})
 }
 
-func (o *Optimizer) executeBatch(ctx context.Context, strats []nfqws.Strategy, group string) []model.ScoredStrategy {
+// executeBatch evaluates a generation. best (nil before the first one) is the
+// current best result that -abort-hopeless evaluations must be able to beat.
+func (o *Optimizer) executeBatch(ctx context.Context, population []evolution.Individual, phase Phase, gen int, best *model.WorkerResult, minWeight float64) []model.ScoredStrategy {
 	var wg sync.WaitGroup
-	results := make([]model.ScoredStrategy, len(strats))
+	results := make([]model.ScoredStrategy, len(population))
+	progress := newProgress(len(population))
 
-	for i, s := range strats {
+	for i, ind := range population {
 		// CHECKPOINT: Don't spawn new goroutines if context is dead
 		if ctx.Err() != nil {
 			break
 		}
 
 		wg.Add(1)
-		go func(idx int, strat nfqws.Strategy) {
+		go func(idx int, ind evolution.Individual) {
 			defer wg.Done()
+			defer progress.done()
 
 			// Check inside goroutine before heavy work
 			if ctx.Err() != nil {
 				return
 			}
 
+			strat := ind.Strategy
+			id := evolution.IndividualID(gen, idx)
+			evaluated := func(scored model.ScoredStrategy) {
+				results[idx] = scored
+				if ctx.Err() != nil {
+					return
+				}
+				o.emit(Event{Type: EventEvaluation, Phase: phase.Name, Group: phase.Group, Gen: gen,
+					ID: id, Parents: ind.Parents, Operator: ind.Operator, Worker: scored.Result.Worker,
+					Args: scored.RawArgs, Score: evolution.CalculateScore(scored.Result, scored.Complexity),
+					DurationMs: scored.Duration.Milliseconds(), Result: &scored.Result, Error: scored.Result.Error})
+			}
+
+			if unsupported := o.Caps.UnsupportedOptions(strat.ToArgs()); len(unsupported) > 0 {
+				evaluated(model.ScoredStrategy{
+					ID:         id,
+					Config:     strat,
+					RawArgs:    strat.ToArgs(),
+					Result:     model.WorkerResult{Error: fmt.Sprintf("nfqws does not support: %v", unsupported)},
+					Complexity: strat.Repeats,
+				})
+				return
+			}
+
 			start := time.Now()
 			req := model.WorkerRequest{
-				StrategyArgs: strat.ToArgs(),
-				TargetGroup:  group,
+				StrategyArgs:  strat.ToArgs(),
+				TargetGroup:   phase.Group,
+				MinWeight:     minWeight,
+				MinThroughput: phase.MinThroughput,
+				Targets:       phase.targets,
+				Resolver:      phase.Resolver,
 			}
 
-			// Pass ctx to Exec
-			res, err := o.Pool.Exec(ctx, req)
+			evalCtx, abort := context.WithCancel(ctx)
+			defer abort()
+
+			// reachable — вес, который оценка еще может набрать
+			reachable := 0.0
+			onEvent := func(ev model.WorkerEvent) {
+				switch ev.Event {
+				case model.EventNfqwsStarted:
+					reachable = ev.Weight
+				case model.EventTarget:
+					progress.target()
+					if !o.AbortHopeless || best == nil || ev.Target.Passed {
+						return
+					}
+					reachable -= ev.Target.Weight
+					// Even if every remaining target passes, the current best stays ahead
+					if reachable < best.Weight || (ev.Target.Required && len(best.RequiredFailed()) == 0) {
+						abort()
+					}
+				}
+			}
+
+			res, err := o.Pool.ExecStream(evalCtx, req, onEvent)
 
 			duration := time.Since(start)
 			scored := model.ScoredStrategy{
+				ID:         id,
 				Config:     strat,
 				RawArgs:    strat.ToArgs(),
 				Duration:   duration,
@@ -114,17 +345,115 @@ func (o *Optimizer) executeBatch(ctx context.Context, strats []nfqws.Strategy, g
 				Complexity: strat.Repeats,
 			}
 
+			// Aborted by us, not by the user: keep the partial result for scoring
+			if err != nil && ctx.Err() == nil && evalCtx.Err() != nil {
+				progress.aborted()
+				err = nil
+			}
+			if scored.Result.Aborted {
+				scored.Result.FillMissing(phase.targets)
+			}
+
 			if err != nil {
 				scored.Result.Error = err.Error()
 			}
 
-			results[idx] = scored
-		}(i, s)
+			evaluated(scored)
+		}(i, ind)
 	}
 	wg.Wait()
 	return results
 }
 
+// excludePoisoned drops targets whose host the system resolver poisons: their failure
+// is DNS, not DPI. A phase resolver or a pinned ip makes them usable again.
+func excludePoisoned(targets []model.Target, resolver string, poisoned []string) []model.Target {
+	if len(poisoned) == 0 || (resolver != "" && resolver != verifier.ResolverSystem) {
+		return targets
+	}
+
+	var kept []model.Target
+	for _, t := range targets {
+		if t.IP == "" && slices.Contains(poisoned, verifier.TargetHost(t)) {
+			fmt.Printf(">>> WARNING: %s is DNS-poisoned, excluded (use -resolver doh or an ip override)\n", t.URL)
+			continue
+		}
+		kept = append(kept, t)
+	}
+	return kept
+}
+
+// eliteThreshold returns the smallest passed weight among the elites of sorted results
+// that pass every must-pass target: a strategy that cannot reach it would not survive
+// selection anyway. Elites failing a must-pass target rank by the penalty, not by
+// weight, so they do not count.
+func eliteThreshold(sorted []model.ScoredStrategy) float64 {
+	if len(sorted) < evolution.ElitesCount {
+		return 0
+	}
+	threshold, found := 0.0, false
+	for _, s := range sorted[:evolution.ElitesCount] {
+		if len(s.Result.RequiredFailed()) > 0 {
+			continue
+		}
+		if !found || s.Result.Weight < threshold {
+			threshold, found = s.Result.Weight, true
+		}
+	}
+	return threshold
+}
+
+// logFailureClasses prints how the checks of the whole generation failed
+func logFailureClasses(results []model.ScoredStrategy) {
+	counts := make(map[string]int)
+	for _, r := range results {
+		for class, n := range r.Result.ClassCounts() {
+			counts[class] += n
+		}
+	}
+	if len(counts) == 0 {
+		return
+	}
+
+	classes := make([]string, 0, len(counts))
+	for c := range counts {
+		classes = append(classes, c)
+	}
+	sort.Slice(classes, func(i, j int) bool { return counts[classes[i]] > counts[classes[j]] })
+
+	parts := make([]string, len(classes))
+	for i, c := range classes {
+		parts[i] = fmt.Sprintf("%s=%d", c, counts[c])
+	}
+	fmt.Printf(">>> Failures: %s\n", strings.Join(parts, " "))
+}
+
+func describeFailure(t model.TargetResult) string {
+	switch t.Class {
+	case model.ClassStalled, model.ClassShort:
+		return fmt.Sprintf("%s after %dB", t.Class, t.Bytes)
+	case model.ClassStatus:
+		return fmt.Sprintf("%s %d", t.Class, t.Status)
+	case model.ClassSlow:
+		return fmt.Sprintf("%s %.0fKB/s", t.Class, float64(t.Throughput)/1024)
+	default:
+		return t.Class
+	}
+}
+
+// logPoolStats prints pool health for the finished generation and lets the scaler react.
+func (o *Optimizer) logPoolStats() {
+	stats := o.Pool.TakeStats()
+	line := fmt.Sprintf(">>> Workers: %d/%d active (infra errors %.0f%%, timeouts %.0f%%)",
+		o.Pool.Limit(), o.Pool.Size(), stats.InfraRate()*100, stats.TimeoutRate()*100)
+
+	if o.Scaler != nil {
+		next, reason := o.Scaler.Adjust(stats)
+		line += fmt.Sprintf(" -> %d next gen (%s)", next, reason)
+	}
+	fmt.Println(line)
+}
+
 func (o *Optimizer) logResultDetails(best *model.ScoredStrategy) {
 	if len(best.Result.Passed) > 0 {
 		fmt.Println("    [+] PASSED:")
@@ -134,8 +463,29 @@ func (o *Optimizer) logResultDetails(best *model.ScoredStrategy) {
 	}
 	if len(best.Result.Failed) > 0 {
 		fmt.Println("    [-] FAILED:")
-		for _, u := range best.Result.Failed {
-			fmt.Printf("        %s\n", u)
+		for _, t := range best.Result.Targets {
+			if !t.Passed {
+				mark := ""
+				if t.Required {
+					mark = " MUST PASS"
+				}
+				fmt.Printf("        %s [%s]%s\n", t.URL, describeFailure(t), mark)
+			}
+		}
+	}
+	if len(best.Result.Targets) > 0 {
+		fmt.Println("    [~] TIMINGS:")
+		fmt.Printf("        checks %dms of %dms round-trip\n", best.Result.CheckMs, best.Duration.Milliseconds())
+		for _, t := range best.Result.Targets {
+			line := fmt.Sprintf("        %5dms (conn %4d tls %4d ttfb %4d) %8dB", t.LatencyMs, t.ConnectMs, t.TLSMs, t.TTFBMs, t.Bytes)
+			if t.Throughput > 0 {
+				line += fmt.Sprintf(" %6.0fKB/s", float64(t.Throughput)/1024)
+			}
+			line += "  " + t.URL
+			if t.Class != model.ClassOK {
+				line += " (" + t.Class + ")"
+			}
+			fmt.Println(line)
 		}
 	}
 }
@@ -0,0 +1,55 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"prikop/internal/evolution"
+	"prikop/internal/model"
+)
+
+// elites builds a sorted generation of ElitesCount results with the given weight;
+// edit sets up individual entries
+func elites(weight float64, edit func([]model.ScoredStrategy)) []model.ScoredStrategy {
+	out := make([]model.ScoredStrategy, evolution.ElitesCount)
+	for i := range out {
+		out[i].Result = model.WorkerResult{Weight: weight, TotalWeight: 10}
+	}
+	if edit != nil {
+		edit(out)
+	}
+	return out
+}
+
+func requiredFailed(r *model.WorkerResult) {
+	r.Targets = []model.TargetResult{{URL: "https://must.pass", Required: true}}
+}
+
+func TestEliteThreshold(t *testing.T) {
+	last := evolution.ElitesCount - 1
+	cases := []struct {
+		name   string
+		sorted []model.ScoredStrategy
+		want   float64
+	}{
+		{"too few to race", elites(5, nil)[:last], 0},
+		{"uniform", elites(5, nil), 5},
+		{"lightest elite", elites(8, func(s []model.ScoredStrategy) { s[last].Result.Weight = 3 }), 3},
+		{"beyond the elites", append(elites(8, nil), model.ScoredStrategy{Result: model.WorkerResult{Weight: 1}}), 8},
+		{"required failure skipped", elites(8, func(s []model.ScoredStrategy) {
+			s[last].Result.Weight = 9
+			requiredFailed(&s[last].Result)
+			s[last-1].Result.Weight = 2
+			requiredFailed(&s[last-1].Result)
+		}), 8},
+		{"every elite failed a required target", elites(8, func(s []model.ScoredStrategy) {
+			for i := range s {
+				requiredFailed(&s[i].Result)
+			}
+		}), 0},
+	}
+	for _, c := range cases {
+		if got := eliteThreshold(c.sorted); got != c.want {
+			t.Errorf("%s: threshold %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package orchestrator
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// progress prints live batch progress based on streamed worker events
+type progress struct {
+	mu       sync.Mutex
+	total    int
+	finished int
+	targets  int
+	aborts   int
+	step     int
+	start    time.Time
+}
+
+func newProgress(total int) *progress {
+	return &progress{
+		total: total,
+		step:  max(1, total/10),
+		start: time.Now(),
+	}
+}
+
+func (p *progress) target() {
+	p.mu.Lock()
+	p.targets++
+	p.mu.Unlock()
+}
+
+func (p *progress) aborted() {
+	p.mu.Lock()
+	p.aborts++
+	p.mu.Unlock()
+}
+
+func (p *progress) done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.finished++
+	if p.finished%p.step != 0 && p.finished != p.total {
+		return
+	}
+	fmt.Printf("    ... %d/%d evaluated, %d target checks, %d aborted (%.1fs)\n",
+		p.finished, p.total, p.targets, p.aborts, time.Since(p.start).Seconds())
+}
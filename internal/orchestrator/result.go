@@ -0,0 +1,125 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PhaseResult — победитель одной фазы
+type PhaseResult struct {
+	Name    string `json:"name"`
+	Group   string `json:"group"`
+	Filters string `json:"filters"`
+	Args    string `json:"args"`
+	Success int    `json:"success"`
+	Total   int    `json:"total"`
+}
+
+// Profile returns the nfqws profile of the phase: filters followed by the strategy
+func (p PhaseResult) Profile() string {
+	return strings.TrimSpace(p.Filters + " " + p.Args)
+}
+
+// Result — итог optimize, из него читают export, verify и монитор
+type Result struct {
+	Time   time.Time     `json:"time"`
+	Phases []PhaseResult `json:"phases"`
+}
+
+// SaveResult writes the result as JSON, replacing the previous one
+func SaveResult(path string, res Result) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadResult reads a result saved by SaveResult
+func LoadResult(path string) (Result, error) {
+	var res Result
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return res, err
+	}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return res, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return res, nil
+}
+
+// Export formats
+const (
+	FormatNfqws  = "nfqws"  // одна командная строка, профили через --new
+	FormatZapret = "zapret" // NFQWS_OPT для config zapret
+	FormatJSON   = "json"
+)
+
+// Render formats the result for deployment
+func (r Result) Render(format string) (string, error) {
+	profiles := make([]string, len(r.Phases))
+	for i, p := range r.Phases {
+		profiles[i] = p.Profile()
+	}
+
+	switch format {
+	case FormatNfqws:
+		return strings.Join(profiles, " --new "), nil
+	case FormatZapret:
+		return "NFQWS_OPT=\"\n" + strings.Join(profiles, " --new\n") + "\n\"", nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(r, "", "  ")
+		return string(data), err
+	}
+	return "", fmt.Errorf("unknown format %q (nfqws, zapret, json)", format)
+}
+
+// ReadStrategy reads a strategy to verify from a file: a saved Result (phase profiles
+// joined with --new, their groups are returned too), a zapret config with NFQWS_OPT
+// or a plain nfqws command line.
+func ReadStrategy(path string) (string, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var res Result
+	if json.Unmarshal(data, &res) == nil && len(res.Phases) > 0 {
+		strategy, _ := res.Render(FormatNfqws)
+		groups := make([]string, len(res.Phases))
+		for i, p := range res.Phases {
+			groups[i] = p.Group
+		}
+		return strategy, groups, nil
+	}
+
+	text := string(data)
+	if _, opt, ok := strings.Cut(text, "NFQWS_OPT=\""); ok {
+		text, _, _ = strings.Cut(opt, "\"")
+	}
+
+	var fields []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, f := range strings.Fields(line) {
+			// zapret substitutes its own hostlists here
+			if f == "<HOSTLIST>" || f == "<HOSTLIST_NOAUTO>" {
+				continue
+			}
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		return "", nil, fmt.Errorf("%s: no nfqws arguments", path)
+	}
+	return strings.Join(fields, " "), nil, nil
+}
@@ -0,0 +1,82 @@
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testResult = Result{Phases: []PhaseResult{
+	{Name: "GENERAL", Group: "general", Filters: "--filter-tcp=80,443", Args: "--dpi-desync=multisplit --dpi-desync-split-pos=1"},
+	{Name: "GOOGLE UDP", Group: "google_udp", Filters: "--filter-udp=443", Args: "--dpi-desync=fake --dpi-desync-repeats=6"},
+}}
+
+func TestRender(t *testing.T) {
+	nfqws := "--filter-tcp=80,443 --dpi-desync=multisplit --dpi-desync-split-pos=1 --new --filter-udp=443 --dpi-desync=fake --dpi-desync-repeats=6"
+	cases := []struct {
+		format string
+		want   string
+	}{
+		{FormatNfqws, nfqws},
+		{FormatZapret, "NFQWS_OPT=\"\n--filter-tcp=80,443 --dpi-desync=multisplit --dpi-desync-split-pos=1 --new\n--filter-udp=443 --dpi-desync=fake --dpi-desync-repeats=6\n\""},
+	}
+	for _, c := range cases {
+		got, err := testResult.Render(c.format)
+		if err != nil {
+			t.Fatalf("%s: %v", c.format, err)
+		}
+		if got != c.want {
+			t.Errorf("%s:\n%s\nwant\n%s", c.format, got, c.want)
+		}
+	}
+	if _, err := testResult.Render("yaml"); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
+
+// TestReadStrategy reads back every export format and the configs users write by hand
+func TestReadStrategy(t *testing.T) {
+	dir := t.TempDir()
+	nfqws, _ := testResult.Render(FormatNfqws)
+	zapret, _ := testResult.Render(FormatZapret)
+	data, _ := testResult.Render(FormatJSON)
+
+	cases := []struct {
+		name     string
+		content  string
+		strategy string
+		groups   []string
+	}{
+		{"result.json", data, nfqws, []string{"general", "google_udp"}},
+		{"zapret.conf", zapret, nfqws, nil},
+		{"nfqws.txt", nfqws + "\n", nfqws, nil},
+		{
+			"config",
+			"# zapret config\nFWTYPE=nftables\nNFQWS_OPT=\"\n# youtube\n--filter-tcp=443 <HOSTLIST> --dpi-desync=fake\n\"\nMODE_FILTER=hostlist\n",
+			"--filter-tcp=443 --dpi-desync=fake",
+			nil,
+		},
+	}
+	for _, c := range cases {
+		path := filepath.Join(dir, c.name)
+		if err := os.WriteFile(path, []byte(c.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		strategy, groups, err := ReadStrategy(path)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if strategy != c.strategy || !slices.Equal(groups, c.groups) {
+			t.Errorf("%s: %q %v, want %q %v", c.name, strategy, groups, c.strategy, c.groups)
+		}
+	}
+
+	empty := filepath.Join(dir, "empty.conf")
+	os.WriteFile(empty, []byte("# nothing\nNFQWS_OPT=\"\n\"\n"), 0o644)
+	if _, _, err := ReadStrategy(empty); err == nil || !strings.Contains(err.Error(), "no nfqws arguments") {
+		t.Errorf("empty config: %v", err)
+	}
+}
@@ -2,88 +2,399 @@ package orchestrator
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"prikop/internal/container"
 	"prikop/internal/model"
+	"prikop/internal/nfqws"
 	"prikop/internal/recon"
+	"prikop/internal/verifier"
 
 	"github.com/moby/moby/client"
 )
 
+// Config is shared by all commands; it is read from -config JSON and overridden by flags
 type Config struct {
-	FakePath    string
-	TargetsPath string
+	FakePath    string `json:"fake_path"`
+	TargetsPath string `json:"targets_path"`
+	Workers     int    `json:"workers"`
+	Adaptive    bool   `json:"adaptive"`
+	// AbortHopeless cancels evaluations that can no longer reach the phase best
+	AbortHopeless bool `json:"abort_hopeless"`
+	// Racing passes the elite threshold to workers so they can stop early
+	Racing bool `json:"racing"`
+	// Resolver is the default target resolver of phases: "system", "doh[:url]", "dot[:host:port]"
+	Resolver string `json:"resolver"`
+	// HistoryPath — JSONL-файл истории разведки (пусто — не сохранять)
+	HistoryPath string `json:"history"`
+	// Label — метка провайдера/ASN для истории ("auto" — спросить ipinfo.io, пусто — "unknown")
+	Label string `json:"label"`
+	// ResultPath — куда optimize сохраняет победителей фаз (читают export и verify)
+	ResultPath string `json:"result"`
+	// Plain disables the terminal UI of optimize, the log is printed as is
+	Plain bool `json:"plain"`
+	// EventLog — JSONL-журнал всех оценок стратегий (пусто — не писать)
+	EventLog string `json:"event_log"`
+	// DiscordVoice/DiscordSSRC — голосовой сервер и SSRC живой сессии Discord:
+	// фаза discord_l7 требует на них IP discovery (пусто — только Google STUN)
+	DiscordVoice string `json:"discord_voice"`
+	DiscordSSRC  uint32 `json:"discord_ssrc"`
+	// WGResponder — host:port запущенного вне DPI prikop wg-responder: цель фазы WireGuard
+	// (пусто — фазы нет, публичных серверов, отвечающих на чужой handshake, не бывает)
+	WGResponder string `json:"wg_responder"`
+	// Phases заменяют встроенные фазы; фаза известной группы начинается со встроенной
+	Phases []Phase `json:"phases"`
+}
+
+// LoadConfig reads a JSON config file over cfg, keeping fields the file does not set
+func LoadConfig(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i, p := range cfg.Phases {
+		if p.Group == "" {
+			return fmt.Errorf("%s: phase %d: group is required", path, i)
+		}
+	}
+	return nil
 }
 
 type Phase struct {
-	Name    string
-	Group   string
-	Gens    int
-	Filters string
+	Name    string `json:"name"`
+	Group   string `json:"group"`
+	Gens    int    `json:"gens"`
+	Filters string `json:"filters"`
+	// MinThroughput (байт/с): крупные цели медленнее этого не засчитываются
+	MinThroughput int64 `json:"min_throughput"`
+	// Targets — файл с описанием целей (по умолчанию targets/<group>.json; пусто при Extra — только они)
+	Targets string `json:"targets"`
+	// Resolver — резолвер целей фазы (пусто — Config.Resolver, затем системный)
+	Resolver string `json:"resolver"`
+	// Seeds — стратегии, с которых начинается поиск (например, прежний победитель)
+	Seeds []nfqws.Strategy `json:"-"`
+	// Extra — цели из конфигурации сверх файла Targets
+	Extra []model.Target `json:"-"`
+
+	targets []model.Target // загруженное содержимое Targets, отправляется воркерам
+}
+
+// withSpec overrides the fields a phase spec sets; Targets and Resolver are up to the caller
+func (p Phase) withSpec(spec Phase) Phase {
+	if spec.Name != "" {
+		p.Name = spec.Name
+	}
+	if spec.Gens > 0 {
+		p.Gens = spec.Gens
+	}
+	if spec.Filters != "" {
+		p.Filters = spec.Filters
+	}
+	if spec.MinThroughput > 0 {
+		p.MinThroughput = spec.MinThroughput
+	}
+	return p
+}
+
+// loadTargets reads the target file of the phase and appends its extra targets
+func (p Phase) loadTargets() (verifier.TargetGroup, error) {
+	if p.Targets == "" && len(p.Extra) > 0 {
+		return verifier.TargetGroup{Name: p.Name, Targets: p.Extra}, nil
+	}
+	g, err := verifier.LoadGroup(p.Targets)
+	if err != nil {
+		return g, err
+	}
+	g.Targets = append(g.Targets, p.Extra...)
+	return g, nil
 }
 
 var pool *container.WorkerPool
 
+// Run performs recon and optimizes every phase
 func Run(cfg Config) {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
+	caps, stop := startPool(ctx, cfg)
+	defer stop()
+
+	resolveLabel(ctx, &cfg)
+
+	phases := loadPhases(cfg)
+	var ui *tui
+	if !cfg.Plain {
+		ui = startTUI(len(phases))
+	}
+	if ui != nil {
+		defer ui.stop()
+	}
+
+	report, ok := runRecon(ctx, cfg, caps, phases)
+	if !ok {
+		return
+	}
+
+	discoveredBins, err := container.DiscoverBinFiles(cfg.FakePath)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		if ui != nil {
+			ui.stop() // log.Fatalf skips deferred calls, give the terminal back first
+		}
+		log.Fatalf("Failed to discover bins: %v", err)
+	}
+	fmt.Printf(">>> Found %d bin files\n", len(discoveredBins))
+
+	optimizer := newOptimizer(cfg, caps)
+	if ui != nil {
+		optimizer.Observers = append(optimizer.Observers, ui.observe)
+	}
+	executePhases(ctx, optimizer, phases, discoveredBins, report, cfg.ResultPath)
+}
+
+// resolveLabel settles the ISP label once for both the recon history and the event log
+func resolveLabel(ctx context.Context, cfg *Config) {
+	if cfg.HistoryPath != "" || cfg.EventLog != "" {
+		cfg.Label = resolvedLabel(ctx, cfg.Label)
+	}
+}
+
+// resolvedLabel returns the configured ISP label. Only "auto" asks ipinfo.io:
+// the request shows the uplink address to a third party.
+func resolvedLabel(ctx context.Context, label string) string {
+	switch label {
+	case "":
+		return recon.UnknownLabel
+	case recon.AutoLabel:
+		return recon.DetectLabel(ctx)
+	}
+	return label
+}
+
+// newOptimizer configures an optimizer on the global pool
+func newOptimizer(cfg Config, caps model.WorkerCapabilities) *Optimizer {
+	optimizer := NewOptimizer(pool)
+	optimizer.Caps = caps
+	optimizer.AbortHopeless = cfg.AbortHopeless
+	optimizer.Racing = cfg.Racing
+	if cfg.Adaptive {
+		optimizer.Scaler = NewScaler(pool)
+		fmt.Printf(">>> Adaptive pool: starting with %d/%d workers\n", pool.Limit(), pool.Size())
+	}
+	if cfg.EventLog != "" {
+		if l, err := openEventLog(cfg.EventLog, cfg.Label); err != nil {
+			fmt.Printf(">>> Event log: %v\n", err)
+		} else {
+			optimizer.Observers = append(optimizer.Observers, l.observe)
+		}
+	}
+	return optimizer
+}
+
+// RunRecon only performs recon, records it in the history and shows what changed
+func RunRecon(cfg Config) {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	caps, stop := startPool(ctx, cfg)
+	defer stop()
+
+	runRecon(ctx, cfg, caps, loadPhases(cfg))
+}
+
+// startPool starts the worker containers; the returned func stops them
+func startPool(ctx context.Context, cfg Config) (model.WorkerCapabilities, func()) {
 	cli, err := client.New(client.FromEnv)
 	if err != nil {
 		log.Fatalf("Error creating docker client: %v", err)
 	}
-	defer cli.Close()
 
 	hostSockDir := os.Getenv("HOST_SOCKET_DIR")
 	if hostSockDir == "" {
 		hostSockDir = "/tmp/prikop_sockets"
 	}
 
-	pool = container.NewWorkerPool(ctx, cli, model.MaxWorkers, hostSockDir)
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = model.MaxWorkers
+	}
+	pool = container.NewWorkerPool(ctx, cli, workers, hostSockDir)
 
 	if err := pool.Start(); err != nil {
+		cli.Close()
 		log.Fatalf("Worker pool start failed: %v", err)
 	}
-	defer func() {
+
+	caps := pool.Capabilities()
+	fmt.Printf(">>> Workers: protocol v%d, nfqws %q, firewall %s, IPv6 %v, %d nfqws options\n",
+		caps.ProtocolVersion, caps.NfqwsVersion, caps.Firewall, caps.IPv6, len(caps.Options))
+
+	return caps, func() {
 		fmt.Println(">>> Cleaning up resources...")
 		pool.Stop()
-	}()
+		cli.Close()
+	}
+}
 
+// loadPhases returns the built-in or configured phases with the default resolver applied
+func loadPhases(cfg Config) []Phase {
+	phases := definePhases(cfg.TargetsPath)
+	if cfg.WGResponder != "" {
+		phases = append(phases, wireGuardPhase(cfg.WGResponder))
+	}
+	if len(cfg.Phases) > 0 {
+		phases = configPhases(cfg, phases)
+	}
+	for i := range phases {
+		if phases[i].Resolver == "" {
+			phases[i].Resolver = cfg.Resolver
+		}
+		if phases[i].Group == "discord_l7" && cfg.DiscordVoice != "" {
+			phases[i].Extra = append(phases[i].Extra, model.Target{
+				URL:      cfg.DiscordVoice,
+				Proto:    verifier.ProtoDiscordIP,
+				SSRC:     cfg.DiscordSSRC,
+				Weight:   2,
+				Required: true,
+			})
+		}
+	}
+	return phases
+}
+
+// wireGuardPhase checks handshakes with the responder stand-in; its only target comes from the config
+func wireGuardPhase(responder string) Phase {
+	_, port, _ := net.SplitHostPort(responder)
+	return Phase{
+		Name:    "WIREGUARD UDP (handshake)",
+		Group:   "wireguard",
+		Gens:    5,
+		Filters: fmt.Sprintf("--filter-udp=%s --filter-l7=wireguard", port),
+		Extra: []model.Target{{
+			URL:          responder,
+			Proto:        verifier.ProtoUDP,
+			Payload:      "wireguard_initiation.bin",
+			ReplyLen:     92,
+			ReplyPattern: "02000000",
+		}},
+	}
+}
+
+// configPhases builds the phases of the config, starting each from the built-in phase of its group
+func configPhases(cfg Config, builtin []Phase) []Phase {
+	phases := make([]Phase, 0, len(cfg.Phases))
+	for _, spec := range cfg.Phases {
+		p := Phase{Name: spec.Group, Group: spec.Group, Gens: AdHocGens}
+		for _, b := range builtin {
+			if b.Group == spec.Group {
+				p = b
+				break
+			}
+		}
+		p = p.withSpec(spec)
+		switch {
+		case spec.Targets != "":
+			p.Targets = spec.Targets
+		case p.Targets == "" && len(p.Extra) == 0:
+			p.Targets = verifier.GroupFile(cfg.TargetsPath, p.Group)
+		}
+		if spec.Resolver != "" {
+			p.Resolver = spec.Resolver
+		}
+		phases = append(phases, p)
+	}
+	return phases
+}
+
+// runRecon probes the DPI and the phase targets, then saves the report to the history.
+// ok is false when the run was interrupted.
+func runRecon(ctx context.Context, cfg Config, caps model.WorkerCapabilities, phases []Phase) (model.ReconReport, bool) {
 	fmt.Println(">>> RUNNING GLOBAL RECONNAISSANCE")
-	report := recon.RunScout(ctx, pool, "google")
+	report := recon.RunScout(ctx, pool, reconGroups(cfg))
 	if ctx.Err() != nil {
-		return
+		return report, false
 	}
-	fmt.Printf("Recon Report: %+v\n", report)
 
-	discoveredBins, err := container.DiscoverBinFiles(cfg.FakePath)
-	if err != nil {
-		if ctx.Err() != nil {
-			return
+	allTargets := phaseTargets(phases)
+	report.DNSPoisoned = recon.ProbeDNS(ctx, pool, allTargets)
+	recon.ProbeBaseline(ctx, pool, allTargets, &report)
+	if ctx.Err() != nil {
+		return report, false
+	}
+
+	saveRecon(ctx, cfg, caps, report)
+	return report, true
+}
+
+// reconGroups loads the targets of the recon probes from the targets directory
+func reconGroups(cfg Config) recon.Groups {
+	groups := make(recon.Groups)
+	for _, name := range recon.ReconGroups {
+		g, err := verifier.LoadGroup(verifier.GroupFile(cfg.TargetsPath, name))
+		if err != nil {
+			fmt.Printf(">>> Recon targets: %v\n", err)
+			continue
 		}
-		log.Fatalf("Failed to discover bins: %v", err)
+		groups[name] = g.Targets
 	}
-	fmt.Printf(">>> Found %d bin files\n", len(discoveredBins))
+	return groups
+}
 
-	phases := definePhases(cfg.TargetsPath)
-	optimizer := NewOptimizer(pool)
+// saveRecon appends the report to the history and prints the changes since the last run
+func saveRecon(ctx context.Context, cfg Config, caps model.WorkerCapabilities, report model.ReconReport) {
+	if cfg.HistoryPath == "" {
+		return
+	}
+
+	label := resolvedLabel(ctx, cfg.Label)
+	rec := recon.Record{Time: time.Now(), Label: label, NfqwsVersion: caps.NfqwsVersion, Report: report}
+
+	history, err := recon.LoadHistory(cfg.HistoryPath)
+	if err != nil {
+		fmt.Printf(">>> Recon history: %v\n", err)
+	}
+	if prev, ok := recon.LastRecord(history, label); ok {
+		recon.PrintDiff(prev, rec)
+	} else {
+		fmt.Printf(">>> Recon history: first run for %q\n", label)
+	}
 
-	executePhases(ctx, optimizer, phases, discoveredBins, report)
+	if err := recon.AppendHistory(cfg.HistoryPath, rec); err != nil {
+		fmt.Printf(">>> Recon history: %v\n", err)
+		return
+	}
+	fmt.Printf(">>> Recon saved to %s\n", cfg.HistoryPath)
 }
 
 func definePhases(targetsPath string) []Phase {
-	return []Phase{
+	phases := []Phase{
 		{
 			Name:    "GENERAL TCP (TCP 16-20 Checker)",
 			Group:   "general",
 			Gens:    8,
 			Filters: "--filter-tcp=80,443",
+			// Стратегия, которая "проходит", но качает 10M.bin со скоростью модема, бесполезна
+			MinThroughput: 128 * 1024,
+		},
+		{
+			// Tamper-опции (hostcase, hostspell, methodeol...) работают только здесь
+			Name:    "PLAIN HTTP (Host header)",
+			Group:   "http",
+			Gens:    5,
+			Filters: "--filter-tcp=80",
 		},
 		{
 			Name:    "GOOGLE TCP",
@@ -97,6 +408,12 @@ func definePhases(targetsPath string) []Phase {
 			Gens:    5,
 			Filters: fmt.Sprintf("--filter-udp=443 --hostlist=%s/google.txt", targetsPath),
 		},
+		{
+			Name:    "DISCORD TCP",
+			Group:   "discord_tcp",
+			Gens:    5,
+			Filters: fmt.Sprintf("--filter-tcp=80,443 --hostlist=%s/discord.txt", targetsPath),
+		},
 		{
 			Name:    "DISCORD UDP (Voice)",
 			Group:   "discord_udp",
@@ -104,46 +421,107 @@ func definePhases(targetsPath string) []Phase {
 			Filters: fmt.Sprintf("--filter-udp=50000-65535,443 --hostlist=%s/discord.txt", targetsPath),
 		},
 		{
-			Name:    "DISCORD UDP (STUN)",
-			Group:   "discord_l7",
+			Name:  "DISCORD UDP (STUN)",
+			Group: "discord_l7",
+			Gens:  5,
+			// Без -discord-voice здесь только Google STUN (19294-19344); голосовые серверы
+			// Discord (IP discovery живой сессии) слушают 50000-65535.
+			// Хостлиста нет: в STUN и IP discovery нет имени хоста, отбор делает --filter-l7
+			Filters: "--filter-udp=19294-19344,50000-65535 --filter-l7=discord,stun",
+		},
+		{
+			Name:    "TORRENT UDP (DHT)",
+			Group:   "torrent_dht",
 			Gens:    5,
-			Filters: fmt.Sprintf("--filter-udp=19294-19344 --filter-l7=discord,stun --hostlist=%s/discord.txt", targetsPath),
+			Filters: "--filter-udp=1024-65535 --filter-l7=dht",
 		},
 	}
+
+	for i := range phases {
+		if phases[i].Targets == "" {
+			phases[i].Targets = verifier.GroupFile(targetsPath, phases[i].Group)
+		}
+	}
+	return phases
+}
+
+// phaseTargets collects the distinct targets of all phases for recon probes
+func phaseTargets(phases []Phase) []model.Target {
+	var all []model.Target
+	seen := make(map[string]bool)
+	for _, p := range phases {
+		g, err := p.loadTargets()
+		if err != nil {
+			continue
+		}
+		for _, t := range g.Targets {
+			if !seen[t.URL] {
+				seen[t.URL] = true
+				all = append(all, t)
+			}
+		}
+	}
+	return all
 }
 
-func executePhases(ctx context.Context, opt *Optimizer, phases []Phase, bins []string, report model.ReconReport) {
+// executePhases optimizes the phases in order and returns their winners
+func executePhases(ctx context.Context, opt *Optimizer, phases []Phase, bins []string, report model.ReconReport, resultPath string) Result {
 	var finalConfigs []string
+	result := Result{Time: time.Now()}
 
 	for _, p := range phases {
 		// CHECKPOINT: Check before starting phase
 		if ctx.Err() != nil {
 			fmt.Println("\n>>> Process aborted by user.")
-			return
+			return result
 		}
 
 		fmt.Printf("\n>>> PHASE: %s\n", p.Name)
 		fmt.Printf(">>> Filters: %s\n", p.Filters)
 
-		best := opt.RunPhase(ctx, p.Group, bins, p.Gens, report)
+		best, skipped := opt.RunPhase(ctx, p, bins, report)
 
 		// Check cancellation return
 		if ctx.Err() != nil {
 			fmt.Println("\n>>> Process aborted by user.")
-			return
+			return result
 		}
 
+		if skipped {
+			fmt.Printf(">>> SKIPPED: %s needs no bypass\n", p.Name)
+			opt.emit(Event{Type: EventPhaseDone, Phase: p.Name, Group: p.Group, Status: "skipped"})
+			continue
+		}
 		if best != nil {
 			strategyArgs := best.Config.ToArgs()
 			fmt.Printf(">>> WINNER: %s\n", strategyArgs)
 			block := fmt.Sprintf("%s %s", p.Filters, strategyArgs)
 			finalConfigs = append(finalConfigs, block)
+			result.Phases = append(result.Phases, PhaseResult{
+				Name:    p.Name,
+				Group:   p.Group,
+				Filters: p.Filters,
+				Args:    strategyArgs,
+				Success: best.Result.SuccessCount,
+				Total:   best.Result.TotalCount,
+			})
+			opt.emit(Event{Type: EventPhaseDone, Phase: p.Name, Group: p.Group, Status: "winner", Args: strategyArgs, Result: &best.Result})
 		} else {
 			fmt.Printf(">>> FAILED: No working strategy found for %s\n", p.Name)
+			opt.emit(Event{Type: EventPhaseDone, Phase: p.Name, Group: p.Group, Status: "failed"})
 		}
 	}
 
 	printFinalConfig(finalConfigs)
+
+	if resultPath != "" && len(result.Phases) > 0 {
+		if err := SaveResult(resultPath, result); err != nil {
+			fmt.Printf(">>> Result: %v\n", err)
+		} else {
+			fmt.Printf(">>> Result saved to %s (prikop export)\n", resultPath)
+		}
+	}
+	return result
 }
 
 func printFinalConfig(configs []string) {
@@ -0,0 +1,91 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"prikop/internal/container"
+	"prikop/internal/model"
+)
+
+const (
+	// Доля транспортных ошибок, при которой пул сжимается
+	maxInfraRate = 0.05
+	// Рост доли таймаутов после расширения пула, при котором откатываемся назад
+	maxTimeoutGrowth = 0.15
+	// Загрузка CPU хоста (loadavg / ядра), при которой пул сжимается
+	maxHostLoad = 1.5
+)
+
+// Scaler adjusts the number of concurrently running workers between generations (AIMD).
+// Too many parallel containers can trigger rate limiting on targets or saturate the uplink,
+// which shows up as transport errors, growing timeouts or host load.
+type Scaler struct {
+	Pool *container.WorkerPool
+	Min  int
+	Max  int
+
+	grown       bool
+	prevLimit   int
+	prevTimeout float64
+}
+
+func NewScaler(pool *container.WorkerPool) *Scaler {
+	s := &Scaler{
+		Pool: pool,
+		Min:  min(model.MinWorkers, pool.Size()),
+		Max:  pool.Size(),
+	}
+	// Start in the middle and let observations decide
+	pool.SetLimit(max(s.Min, s.Max/2))
+	return s
+}
+
+// Adjust picks the next concurrency limit from the stats of the finished generation.
+func (s *Scaler) Adjust(stats container.PoolStats) (int, string) {
+	cur := s.Pool.Limit()
+	load := hostLoad()
+	timeoutRate := stats.TimeoutRate()
+
+	next := cur
+	reason := "steady"
+
+	switch {
+	case stats.InfraRate() > maxInfraRate:
+		next, reason = cur*2/3, "transport errors"
+	case load > maxHostLoad:
+		next, reason = cur*2/3, fmt.Sprintf("host load %.2f", load)
+	case s.grown && timeoutRate > s.prevTimeout+maxTimeoutGrowth:
+		next, reason = s.prevLimit, "timeouts grew after scale-up"
+	case stats.Execs > 0:
+		next, reason = cur+max(1, cur/4), "healthy"
+	}
+
+	next = max(s.Min, min(next, s.Max))
+	s.grown = next > cur
+	s.prevLimit = cur
+	s.prevTimeout = timeoutRate
+
+	s.Pool.SetLimit(next)
+	return next, reason
+}
+
+// hostLoad returns 1-minute load average normalized by CPU count (0 if unavailable).
+func hostLoad() float64 {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0
+	}
+	load, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	return load / float64(runtime.NumCPU())
+}
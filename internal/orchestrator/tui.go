@@ -0,0 +1,326 @@
+package orchestrator
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"prikop/internal/model"
+)
+
+const (
+	tuiRefresh = 250 * time.Millisecond
+	// tuiRateWindow — окно, по которому считается скорость оценок
+	tuiRateWindow = 10 * time.Second
+	tuiLeaders    = 8
+	tuiHeatCols   = 5
+	tuiLogKeep    = 200
+
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiDim   = "\x1b[2m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiYell  = "\x1b[33m"
+)
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	_, err := unix.IoctlGetTermios(int(f.Fd()), unix.TCGETS)
+	return err == nil
+}
+
+// leader — стратегия таблицы лидеров с результатом для тепловой карты
+type leader struct {
+	args   string
+	score  float64
+	result model.WorkerResult
+}
+
+// tui draws optimizer progress on the terminal. Everything the rest of the code
+// prints to stdout, stderr or the log package is captured and shown in the log
+// panel at the bottom.
+type tui struct {
+	term   *os.File
+	stdout *os.File
+	stderr *os.File
+	pipe   *os.File
+	done   chan struct{}
+	wg     sync.WaitGroup
+
+	mu         sync.Mutex
+	phases     int
+	phaseNum   int
+	phase      string
+	phaseStart time.Time
+	gen, gens  int
+	size       int
+	evaluated  int
+	errors     int
+	evals      []time.Time
+	leaders    []leader
+	logs       []string
+}
+
+// startTUI takes over the terminal, nil when stdout is not one (plain log then)
+func startTUI(phases int) *tui {
+	if !isTerminal(os.Stdout) {
+		return nil
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil
+	}
+
+	t := &tui{term: os.Stdout, stdout: os.Stdout, stderr: os.Stderr, pipe: w, done: make(chan struct{}), phases: phases}
+	os.Stdout, os.Stderr = w, w
+	// log запомнил os.Stderr при старте, подмена переменной его не касается
+	log.SetOutput(w)
+	fmt.Fprint(t.term, "\x1b[?1049h\x1b[?25l") // alternate screen, hide cursor
+
+	t.wg.Add(2)
+	go t.capture(r)
+	go t.loop()
+	return t
+}
+
+// stop restores the terminal and prints the tail of the log, so the final
+// configuration stays on screen
+func (t *tui) stop() {
+	close(t.done)
+	os.Stdout, os.Stderr = t.stdout, t.stderr
+	log.SetOutput(t.stderr)
+	t.pipe.Close()
+	t.wg.Wait()
+
+	fmt.Fprint(t.term, "\x1b[?25h\x1b[?1049l")
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, line := range t.logs {
+		fmt.Fprintln(t.term, line)
+	}
+}
+
+// capture collects the lines printed while the UI is active
+func (t *tui) capture(r *os.File) {
+	defer t.wg.Done()
+	defer r.Close()
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		t.mu.Lock()
+		t.logs = append(t.logs, sc.Text())
+		if len(t.logs) > tuiLogKeep {
+			t.logs = t.logs[len(t.logs)-tuiLogKeep:]
+		}
+		t.mu.Unlock()
+	}
+}
+
+func (t *tui) loop() {
+	defer t.wg.Done()
+	ticker := time.NewTicker(tuiRefresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+			t.draw()
+		}
+	}
+}
+
+// observe is the optimizer observer of the UI
+func (t *tui) observe(ev Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch ev.Type {
+	case EventPhaseStarted:
+		t.phaseNum++
+		t.phase, t.gens, t.gen = ev.Phase, ev.Gens, 0
+		t.phaseStart = ev.Time
+		t.leaders, t.evaluated, t.errors, t.size = nil, 0, 0, 0
+	case EventGeneration:
+		t.gen, t.size, t.evaluated = ev.Gen, ev.Size, 0
+	case EventEvaluation:
+		t.evaluated++
+		t.evals = append(t.evals, ev.Time)
+		if ev.Error != "" {
+			t.errors++
+		}
+		if ev.Result != nil {
+			t.rank(leader{args: ev.Args, score: ev.Score, result: *ev.Result})
+		}
+	}
+}
+
+// rank keeps the best distinct strategies of the phase
+func (t *tui) rank(l leader) {
+	for i := range t.leaders {
+		if t.leaders[i].args == l.args {
+			if l.score <= t.leaders[i].score {
+				return
+			}
+			t.leaders = append(t.leaders[:i], t.leaders[i+1:]...)
+			break
+		}
+	}
+	t.leaders = append(t.leaders, l)
+	sort.SliceStable(t.leaders, func(i, j int) bool { return t.leaders[i].score > t.leaders[j].score })
+	if len(t.leaders) > tuiLeaders {
+		t.leaders = t.leaders[:tuiLeaders]
+	}
+}
+
+// rate returns evaluations per second over the recent window
+func (t *tui) rate(now time.Time) float64 {
+	cut := 0
+	for cut < len(t.evals) && now.Sub(t.evals[cut]) > tuiRateWindow {
+		cut++
+	}
+	t.evals = t.evals[cut:]
+	if len(t.evals) == 0 {
+		return 0
+	}
+	return float64(len(t.evals)) / tuiRateWindow.Seconds()
+}
+
+func (t *tui) draw() {
+	width, height := 120, 40
+	if ws, err := unix.IoctlGetWinsize(int(t.term.Fd()), unix.TIOCGWINSZ); err == nil && ws.Col > 0 {
+		width, height = int(ws.Col), int(ws.Row)
+	}
+
+	t.mu.Lock()
+	lines := t.render(width, time.Now())
+	logs := t.logs
+	t.mu.Unlock()
+
+	// The log panel gets whatever height is left
+	room := height - len(lines) - 2
+	if room > 0 {
+		lines = append(lines, "", ansiBold+"LOG"+ansiReset)
+		if len(logs) > room-1 {
+			logs = logs[len(logs)-(room-1):]
+		}
+		for _, l := range logs {
+			lines = append(lines, ansiDim+clip(l, width)+ansiReset)
+		}
+	}
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for _, l := range lines {
+		b.WriteString(l)
+		b.WriteString("\x1b[K\n")
+	}
+	b.WriteString("\x1b[J")
+	fmt.Fprint(t.term, b.String())
+}
+
+// render builds the status panels; the caller holds t.mu
+func (t *tui) render(width int, now time.Time) []string {
+	var lines []string
+
+	if t.phase == "" {
+		lines = append(lines, ansiBold+"PRIKOP"+ansiReset+"  reconnaissance...")
+	} else {
+		lines = append(lines, fmt.Sprintf("%sPHASE %d/%d%s  %s  %s(%s)%s",
+			ansiBold, t.phaseNum, t.phases, ansiReset, clip(t.phase, width/2), ansiDim, now.Sub(t.phaseStart).Round(time.Second), ansiReset))
+		lines = append(lines, fmt.Sprintf("GEN %d/%d %s  %d/%d evaluated  %.1f evals/s  %d errors",
+			t.gen, t.gens, bar(t.gen, max(t.gens, 1), 20), t.evaluated, t.size, t.rate(now), t.errors))
+	}
+
+	if pool != nil {
+		lines = append(lines, fmt.Sprintf("WORKERS %s %d busy / %d limit / %d size, %d healthy",
+			bar(pool.Busy(), max(pool.Size(), 1), 20), pool.Busy(), pool.Limit(), pool.Size(), pool.Healthy()))
+	}
+
+	if len(t.leaders) == 0 {
+		return lines
+	}
+
+	lines = append(lines, "", ansiBold+"LEADERBOARD"+ansiReset)
+	for i, l := range t.leaders {
+		lines = append(lines, clip(fmt.Sprintf(" #%d %7.1f  %3d/%-3d  %s",
+			i+1, l.score, l.result.SuccessCount, l.result.TotalCount, l.args), width))
+	}
+
+	lines = append(lines, "", ansiBold+"TARGETS"+ansiReset+ansiDim+"  (columns: leaderboard #1..#5, class of #1)"+ansiReset)
+	lines = append(lines, t.heatmap(width)...)
+	return lines
+}
+
+// heatmap shows target results of the top strategies, one row per target
+func (t *tui) heatmap(width int) []string {
+	cols := t.leaders[:min(tuiHeatCols, len(t.leaders))]
+	byURL := make([]map[string]model.TargetResult, len(cols))
+	for i, l := range cols {
+		byURL[i] = make(map[string]model.TargetResult, len(l.result.Targets))
+		for _, r := range l.result.Targets {
+			byURL[i][r.URL] = r
+		}
+	}
+
+	urls := make([]string, 0, len(cols[0].result.Targets))
+	for _, r := range cols[0].result.Targets {
+		urls = append(urls, r.URL)
+	}
+	sort.Strings(urls)
+
+	var lines []string
+	for _, url := range urls {
+		var row strings.Builder
+		row.WriteString(" ")
+		for i := range cols {
+			r, ok := byURL[i][url]
+			switch {
+			case !ok:
+				row.WriteString(ansiDim + "·· " + ansiReset)
+			case r.Passed:
+				row.WriteString(ansiGreen + "██ " + ansiReset)
+			case r.Class == model.ClassCancelled:
+				row.WriteString(ansiYell + "▒▒ " + ansiReset)
+			default:
+				row.WriteString(ansiRed + "██ " + ansiReset)
+			}
+		}
+		label := url
+		if r, ok := byURL[0][url]; ok && !r.Passed {
+			label += " (" + describeFailure(r) + ")"
+		}
+		row.WriteString(clip(label, width-3*len(cols)-2))
+		lines = append(lines, row.String())
+	}
+	return lines
+}
+
+// bar draws a progress bar of n out of total
+func bar(n, total, width int) string {
+	filled := min(width, n*width/total)
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", width-filled) + "]"
+}
+
+// clip cuts s to width runes
+func clip(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	return string(r[:width-1]) + "…"
+}
@@ -0,0 +1,221 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+
+	"prikop/internal/model"
+)
+
+// TargetStats — итог одной цели за все прогоны verify
+type TargetStats struct {
+	URL       string
+	Required  bool
+	Passed    int
+	Runs      int
+	Latencies []int64
+	Classes   map[string]int
+}
+
+// Rate returns the pass rate of the target in percent
+func (t *TargetStats) Rate() int {
+	if t.Runs == 0 {
+		return 0
+	}
+	return t.Passed * 100 / t.Runs
+}
+
+// GroupStats — итог группы целей за все прогоны verify
+type GroupStats struct {
+	Group     string
+	Runs      int
+	RunErrors []string
+	Targets   []*TargetStats
+}
+
+// Rate returns the share of passed checks over all runs and targets in percent
+func (g GroupStats) Rate() int {
+	passed, total := 0, 0
+	for _, t := range g.Targets {
+		passed += t.Passed
+		total += t.Runs
+	}
+	if total == 0 {
+		return 0
+	}
+	return passed * 100 / total
+}
+
+// verifyGroup evaluates the strategy runs times on the phase targets and collects per-target stats.
+// A run that fails as a whole (nfqws crash, infra error) counts as failed for every target.
+func verifyGroup(ctx context.Context, strategy string, phase Phase, runs int) (GroupStats, error) {
+	g, err := phase.loadTargets()
+	if err != nil {
+		return GroupStats{}, err
+	}
+
+	stats := GroupStats{Group: phase.Group}
+	byURL := make(map[string]*TargetStats, len(g.Targets))
+	for _, t := range g.Targets {
+		ts := &TargetStats{URL: t.URL, Required: t.Required, Classes: make(map[string]int)}
+		byURL[t.URL] = ts
+		stats.Targets = append(stats.Targets, ts)
+	}
+
+	for run := 0; run < runs; run++ {
+		if ctx.Err() != nil {
+			return stats, ctx.Err()
+		}
+		res, err := pool.Exec(ctx, model.WorkerRequest{
+			StrategyArgs:  strategy,
+			TargetGroup:   phase.Group,
+			MinThroughput: phase.MinThroughput,
+			Targets:       g.Targets,
+			Resolver:      phase.Resolver,
+		})
+		if err == nil && res.Error != "" {
+			err = fmt.Errorf("%s", res.Error)
+		}
+		stats.Runs++
+		if err != nil {
+			stats.RunErrors = append(stats.RunErrors, err.Error())
+			for _, ts := range stats.Targets {
+				ts.Runs++
+				ts.Classes[model.ClassError]++
+			}
+			continue
+		}
+
+		seen := make(map[string]bool, len(res.Targets))
+		for _, t := range res.Targets {
+			ts, ok := byURL[t.URL]
+			if !ok {
+				continue
+			}
+			seen[t.URL] = true
+			ts.Runs++
+			if t.LatencyMs > 0 {
+				ts.Latencies = append(ts.Latencies, t.LatencyMs)
+			}
+			if t.Passed {
+				ts.Passed++
+			} else {
+				ts.Classes[describeFailure(t)]++
+			}
+		}
+		// Targets the worker did not report (aborted run) failed this run
+		for url, ts := range byURL {
+			if !seen[url] {
+				ts.Runs++
+				ts.Classes[model.ClassCancelled]++
+			}
+		}
+	}
+	return stats, nil
+}
+
+// printGroupStats prints the pass rate, latency and failure classes of every target
+func printGroupStats(g GroupStats, threshold int) {
+	verdict := "OK"
+	if g.Rate() < threshold {
+		verdict = "BELOW THRESHOLD"
+	}
+	fmt.Printf("\n>>> %s: %d%% of checks passed over %d runs (threshold %d%%) — %s\n",
+		g.Group, g.Rate(), g.Runs, threshold, verdict)
+	for i, e := range g.RunErrors {
+		fmt.Printf("    [!] run error %d: %s\n", i+1, e)
+	}
+
+	fmt.Printf("    %-6s %-5s %-15s %s\n", "PASS", "RATE", "LATENCY p50/max", "TARGET")
+	for _, t := range g.Targets {
+		name := t.URL
+		if t.Required {
+			name += " (required)"
+		}
+		latency := "-"
+		if len(t.Latencies) > 0 {
+			l := slices.Clone(t.Latencies)
+			slices.Sort(l)
+			latency = fmt.Sprintf("%d/%dms", l[len(l)/2], l[len(l)-1])
+		}
+		fmt.Printf("    %-6s %4d%% %-15s %s%s\n",
+			fmt.Sprintf("%d/%d", t.Passed, t.Runs), t.Rate(), latency, name, formatClasses(t.Classes))
+	}
+}
+
+// formatClasses lists failure classes by frequency: " [timeout×2, rst]"
+func formatClasses(classes map[string]int) string {
+	if len(classes) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(classes))
+	for c := range classes {
+		names = append(names, c)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if classes[names[i]] != classes[names[j]] {
+			return classes[names[i]] > classes[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	for i, c := range names {
+		if classes[c] > 1 {
+			names[i] = fmt.Sprintf("%s×%d", c, classes[c])
+		}
+	}
+	return " [" + strings.Join(names, ", ") + "]"
+}
+
+// isMultiProfile reports whether the args are a full nfqws config with its own filters
+func isMultiProfile(strategy string) bool {
+	return strings.Contains(strategy, "--new") || strings.Contains(strategy, "--filter-")
+}
+
+// Verify evaluates one strategy runs times on every group and prints per-target statistics.
+// Without explicit groups a single profile is checked on "general", a multi-profile
+// config on the groups of all phases. The exit code is 1 when a group passes
+// less than threshold percent of its checks, so the command can run from cron.
+func Verify(cfg Config, strategy string, groups []string, runs, threshold int) int {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if len(groups) == 0 {
+		if isMultiProfile(strategy) {
+			for _, p := range loadPhases(cfg) {
+				groups = append(groups, p.Group)
+			}
+		} else {
+			groups = []string{"general"}
+		}
+	}
+
+	_, stop := startPool(ctx, cfg)
+	defer stop()
+
+	fmt.Printf(">>> VERIFY %q\n>>> %d runs on %s\n", strategy, runs, strings.Join(groups, ", "))
+
+	code := 0
+	for _, group := range groups {
+		stats, err := verifyGroup(ctx, strategy, findPhase(cfg, group), runs)
+		if ctx.Err() != nil {
+			fmt.Println("\n>>> Verify aborted by user.")
+			return 1
+		}
+		if err != nil {
+			fmt.Printf("\n>>> %s: %v\n", group, err)
+			code = 1
+			continue
+		}
+		printGroupStats(stats, threshold)
+		if stats.Rate() < threshold {
+			code = 1
+		}
+	}
+	return code
+}
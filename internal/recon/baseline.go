@@ -0,0 +1,63 @@
+package recon
+
+import (
+	"context"
+	"fmt"
+
+	"prikop/internal/container"
+	"prikop/internal/model"
+	"prikop/internal/verifier"
+)
+
+// Baseline checks targets without NFQUEUE and nfqws, i.e. the network as the ISP serves it
+func Baseline(ctx context.Context, pool *container.WorkerPool, group string, targets []model.Target, resolver string) (model.WorkerResult, error) {
+	return pool.Exec(ctx, model.WorkerRequest{
+		TargetGroup: group,
+		Targets:     targets,
+		Resolver:    resolver,
+		Baseline:    true,
+	})
+}
+
+// ProbeBaseline reports which targets of all phases are open, blocked or dead without bypass
+func ProbeBaseline(ctx context.Context, pool *container.WorkerPool, targets []model.Target, r *model.ReconReport) {
+	fmt.Printf("    [?] Baseline without bypass (%d targets)... ", len(targets))
+
+	res, err := Baseline(ctx, pool, "baseline", targets, "")
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return
+	}
+
+	for _, t := range res.Targets {
+		switch {
+		case t.Passed:
+			r.BaselineOpen++
+		case verifier.DeadClass(t.Class):
+			r.BaselineDead++
+		default:
+			r.BaselineBlocked++
+		}
+	}
+	fmt.Printf("%d open, %d blocked, %d dead\n", r.BaselineOpen, r.BaselineBlocked, r.BaselineDead)
+	PrintBaseline(res.Targets)
+}
+
+// PrintBaseline prints the per-target baseline status
+func PrintBaseline(targets []model.TargetResult) {
+	for _, t := range targets {
+		status := "open"
+		switch {
+		case t.Passed:
+		case verifier.DeadClass(t.Class):
+			status = "dead"
+		default:
+			status = "blocked"
+		}
+		detail := t.Class
+		if t.Error != "" && !t.Passed {
+			detail += ": " + t.Error
+		}
+		fmt.Printf("        [%-7s] %s %s\n", status, t.URL, detail)
+	}
+}
@@ -0,0 +1,61 @@
+package recon
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"prikop/internal/container"
+	"prikop/internal/model"
+	"prikop/internal/verifier"
+)
+
+// ProbeDNS compares the container resolver with DoH for every target and
+// returns the hosts whose system answer is poisoned. Such targets fail with
+// any strategy: they need a DoH/DoT resolver or an ip override.
+func ProbeDNS(ctx context.Context, pool *container.WorkerPool, targets []model.Target) []string {
+	fmt.Printf("    [?] Probing DNS (system resolver vs DoH, %d targets)... ", len(targets))
+
+	res, err := pool.Exec(ctx, model.WorkerRequest{
+		TargetGroup: "dns",
+		CheckMode:   verifier.ModeDNS,
+		Targets:     targets,
+	})
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return nil
+	}
+
+	var poisoned []string
+	for _, t := range res.Targets {
+		if t.Class == model.ClassDNS {
+			poisoned = append(poisoned, verifier.TargetHost(model.Target{URL: t.URL, Proto: protoOf(targets, t.URL)}))
+		}
+	}
+	slices.Sort(poisoned)
+	poisoned = slices.Compact(poisoned)
+
+	if len(poisoned) == 0 {
+		fmt.Println("CLEAN")
+	} else {
+		fmt.Printf("POISONED (%d hosts)\n", len(poisoned))
+	}
+	for _, t := range res.Targets {
+		switch {
+		case t.Class == model.ClassDNS:
+			fmt.Printf("        [poisoned] %s: %s\n", t.URL, t.Error)
+		case t.Error != "":
+			fmt.Printf("        [note]     %s: %s\n", t.URL, t.Error)
+		}
+	}
+	return poisoned
+}
+
+func protoOf(targets []model.Target, url string) string {
+	for _, t := range targets {
+		if t.URL == url {
+			return t.Proto
+		}
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package recon
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"prikop/internal/container"
+	"prikop/internal/model"
+)
+
+type fingerprintProbe struct {
+	tech  string
+	group string
+	args  string
+}
+
+// Каждая техника проверяется поодиночке, чтобы видеть, на что DPI реагирует
+var fingerprintProbes = []fingerprintProbe{
+	{model.TechFake, GroupTCP, "--dpi-desync=fake"},
+	{model.TechMd5Sig, GroupTCP, "--dpi-desync=fake --dpi-desync-fooling=md5sig"},
+	{model.TechBadSeq, GroupTCP, "--dpi-desync=fake --dpi-desync-fooling=badseq"},
+	{model.TechBadSum, GroupTCP, "--dpi-desync=fake --dpi-desync-fooling=badsum"},
+	{model.TechTs, GroupTCP, "--dpi-desync=fake --dpi-desync-fooling=ts"},
+	{model.TechDatanoack, GroupTCP, "--dpi-desync=fake --dpi-desync-fooling=datanoack"},
+	{model.TechSplitPos1, GroupTCP, "--dpi-desync=multisplit --dpi-desync-split-pos=1"},
+	{model.TechSplitSNI, GroupTCP, "--dpi-desync=multisplit --dpi-desync-split-pos=midsld"},
+	{model.TechDisorder, GroupTCP, "--dpi-desync=multidisorder --dpi-desync-split-pos=1"},
+	{model.TechSeqOvl, GroupTCP, "--dpi-desync=multisplit --dpi-desync-split-pos=2 --dpi-desync-split-seqovl=1"},
+	{model.TechWSSize, GroupTCP, "--wssize=1:6"},
+	{model.TechQuicFake, GroupUDP, "--dpi-desync=fake --dpi-desync-repeats=6"},
+}
+
+// probeFingerprint runs every probe and a control per group, all in parallel on the pool
+func probeFingerprint(ctx context.Context, pool *container.WorkerPool, groups Groups, r *model.ReconReport) {
+	fmt.Printf("    [?] Fingerprinting DPI (%d techniques)...\n", len(fingerprintProbes))
+
+	controls := make(map[string]int)
+	controlErrs := make(map[string]string)
+	entries := make([]model.FingerprintEntry, len(fingerprintProbes))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, group := range []string{GroupTCP, GroupUDP} {
+		req, ok := groups.request(group, "")
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(group string) {
+			defer wg.Done()
+			res, err := pool.Exec(ctx, req)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				controlErrs[group] = err.Error()
+			case res.Error != "":
+				controlErrs[group] = res.Error
+			default:
+				controls[group] = res.SuccessCount
+			}
+		}(group)
+	}
+
+	for i, p := range fingerprintProbes {
+		wg.Add(1)
+		go func(i int, p fingerprintProbe) {
+			defer wg.Done()
+			e := model.FingerprintEntry{Technique: p.tech, Group: p.group, Args: p.args}
+			req, ok := groups.request(p.group, p.args)
+			if !ok {
+				e.Err = fmt.Sprintf("no %s targets", p.group)
+				entries[i] = e
+				return
+			}
+			res, err := pool.Exec(ctx, req)
+			switch {
+			case err != nil:
+				e.Err = err.Error()
+			case res.Error != "":
+				e.Err = res.Error
+			default:
+				e.Passed, e.Total = res.SuccessCount, res.TotalCount
+			}
+			entries[i] = e
+		}(i, p)
+	}
+	wg.Wait()
+
+	// Без контроля пробу не с чем сравнить: вся группа считается ошибкой
+	for i := range entries {
+		entries[i].Control = controls[entries[i].Group]
+		if msg, ok := controlErrs[entries[i].Group]; ok && entries[i].Err == "" {
+			entries[i].Err = "control: " + msg
+		}
+	}
+	r.Fingerprint = entries
+
+	// QUIC: ни контроль, ни фейк не проходят — DPI режет протокол целиком
+	for _, e := range entries {
+		if e.Technique == model.TechQuicFake && e.Err == "" {
+			r.QUICDropped = e.Control == 0 && e.Passed == 0
+		}
+	}
+
+	printFingerprint(r)
+}
+
+func printFingerprint(r *model.ReconReport) {
+	fmt.Printf("        %-12s %-11s %7s %8s  %s\n", "TECHNIQUE", "GROUP", "PASSED", "CONTROL", "EFFECT")
+	for _, e := range r.Fingerprint {
+		effect := "ignored"
+		switch {
+		case e.Err != "":
+			effect = "error: " + e.Err
+		case e.Effective():
+			effect = "WORKS"
+		case e.Passed < e.Control:
+			effect = "harmful"
+		case e.Control == e.Total:
+			effect = "n/a (control passed)"
+		}
+		fmt.Printf("        %-12s %-11s %3d/%-3d %8d  %s\n", e.Technique, e.Group, e.Passed, e.Total, e.Control, effect)
+	}
+	if r.QUICDropped {
+		fmt.Println("        QUIC is dropped outright: fakes do not help, clients will fall back to TCP")
+	}
+}
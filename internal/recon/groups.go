@@ -0,0 +1,23 @@
+package recon
+
+import "prikop/internal/model"
+
+// Группы целей, на которых работают пробы разведки
+const (
+	GroupTCP    = "google_tcp"
+	GroupUDP    = "google_udp"
+	GroupFreeze = "general"
+)
+
+// ReconGroups lists the target groups recon probes need
+var ReconGroups = []string{GroupTCP, GroupUDP, GroupFreeze}
+
+// Groups holds the targets of the recon groups, loaded by the orchestrator from its
+// targets directory: workers then check exactly those, not the files baked into the image.
+type Groups map[string][]model.Target
+
+// request builds a probe on the group targets; ok is false when the group is not loaded
+func (g Groups) request(group, args string) (model.WorkerRequest, bool) {
+	targets := g[group]
+	return model.WorkerRequest{StrategyArgs: args, TargetGroup: group, Targets: targets}, len(targets) > 0
+}
@@ -0,0 +1,159 @@
+package recon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"prikop/internal/model"
+)
+
+// Record — одна запись истории разведки (строка JSONL)
+type Record struct {
+	Time         time.Time         `json:"time"`
+	Label        string            `json:"label"` // ISP/ASN, по нему сравниваются прогоны
+	NfqwsVersion string            `json:"nfqws_version"`
+	Report       model.ReconReport `json:"report"`
+}
+
+// AppendHistory appends the record to the JSONL history file
+func AppendHistory(path string, rec Record) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(rec)
+}
+
+// LoadHistory reads all records, a missing file is an empty history
+func LoadHistory(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var recs []Record
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for sc.Scan() {
+		var rec Record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			continue // damaged line, keep the rest
+		}
+		recs = append(recs, rec)
+	}
+	return recs, sc.Err()
+}
+
+// LastRecord returns the latest record with the label
+func LastRecord(recs []Record, label string) (Record, bool) {
+	for i := len(recs) - 1; i >= 0; i-- {
+		if recs[i].Label == label {
+			return recs[i], true
+		}
+	}
+	return Record{}, false
+}
+
+// Метки провайдера: AutoLabel включает запрос к ipinfo.io, UnknownLabel — метка по умолчанию
+const (
+	AutoLabel    = "auto"
+	UnknownLabel = "unknown"
+)
+
+// DetectLabel asks ipinfo.io for the ASN of the current uplink, UnknownLabel on failure
+func DetectLabel(ctx context.Context) string {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://ipinfo.io/json", nil)
+	if err != nil {
+		return UnknownLabel
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return UnknownLabel
+	}
+	defer resp.Body.Close()
+
+	var info struct {
+		Org string `json:"org"` // "AS12389 PJSC Rostelecom"
+	}
+	if json.NewDecoder(resp.Body).Decode(&info) != nil || info.Org == "" {
+		return UnknownLabel
+	}
+	return info.Org
+}
+
+// Diff lists the DPI behaviours that changed between two reports
+func Diff(prev, cur model.ReconReport) []string {
+	var out []string
+	flag := func(name string, a, b bool) {
+		if a != b {
+			out = append(out, fmt.Sprintf("%s: %v -> %v", name, a, b))
+		}
+	}
+
+	flag("ipfrag1 works", prev.IPFragWorks, cur.IPFragWorks)
+	flag("badsum works", prev.BadSumWorks, cur.BadSumWorks)
+	flag("16-20KB freeze", prev.FreezeCutoff, cur.FreezeCutoff)
+	flag("QUIC dropped", prev.QUICDropped, cur.QUICDropped)
+	if prev.TTLDistance != cur.TTLDistance {
+		out = append(out, fmt.Sprintf("TTL distance: %d -> %d", prev.TTLDistance, cur.TTLDistance))
+	}
+
+	for _, e := range cur.Fingerprint {
+		i := slices.IndexFunc(prev.Fingerprint, func(p model.FingerprintEntry) bool { return p.Technique == e.Technique })
+		if i < 0 || e.Err != "" || prev.Fingerprint[i].Err != "" {
+			continue
+		}
+		flag("technique "+e.Technique, prev.Fingerprint[i].Effective(), e.Effective())
+	}
+
+	for _, h := range cur.DNSPoisoned {
+		if !slices.Contains(prev.DNSPoisoned, h) {
+			out = append(out, "DNS poisoned now: "+h)
+		}
+	}
+	for _, h := range prev.DNSPoisoned {
+		if !slices.Contains(cur.DNSPoisoned, h) {
+			out = append(out, "DNS no longer poisoned: "+h)
+		}
+	}
+
+	if prev.BaselineBlocked != cur.BaselineBlocked || prev.BaselineOpen != cur.BaselineOpen {
+		out = append(out, fmt.Sprintf("baseline open/blocked: %d/%d -> %d/%d",
+			prev.BaselineOpen, prev.BaselineBlocked, cur.BaselineOpen, cur.BaselineBlocked))
+	}
+	return out
+}
+
+// PrintDiff prints what changed since the previous run with the same label
+func PrintDiff(prev Record, cur Record) {
+	fmt.Printf(">>> Recon changes since %s (%s, nfqws %s):\n", prev.Time.Format(time.DateTime), prev.Label, prev.NfqwsVersion)
+	if prev.NfqwsVersion != cur.NfqwsVersion {
+		fmt.Printf("    ! nfqws version changed, differences may be ours, not the ISP's\n")
+	}
+	changes := Diff(prev.Report, cur.Report)
+	if len(changes) == 0 {
+		fmt.Println("    no DPI behaviour changes")
+		return
+	}
+	fmt.Println("    " + strings.Join(changes, "\n    "))
+}
@@ -6,24 +6,22 @@ import (
 
 	"prikop/internal/container"
 	"prikop/internal/model"
+	"prikop/internal/verifier"
 )
 
 // RunScout performs active reconnaissance (middlebox fingerprinting)
 // Now uses WorkerPool for fast execution instead of spinning up new containers.
-func RunScout(ctx context.Context, pool *container.WorkerPool, group string) model.ReconReport {
+func RunScout(ctx context.Context, pool *container.WorkerPool, groups Groups) model.ReconReport {
 	fmt.Println(">>> STARTING ACTIVE RECONNAISSANCE...")
 	r := model.ReconReport{}
 
 	// 1. Check Fragmentation (ipfrag1)
 	fmt.Print("    [?] Probing Fragmentation (ipfrag1)... ")
 
-	fragReq := model.WorkerRequest{
-		StrategyArgs: "--dpi-desync=ipfrag1 --dpi-desync-repeats=2",
-		TargetGroup:  group,
-	}
-
-	fragRes, err := pool.Exec(ctx, fragReq)
-	if err == nil && fragRes.Success {
+	fragReq, ok := groups.request(GroupTCP, "--dpi-desync=ipfrag1 --dpi-desync-repeats=2")
+	if !ok {
+		fmt.Printf("SKIPPED (no %s targets)\n", GroupTCP)
+	} else if fragRes, err := pool.Exec(ctx, fragReq); err == nil && fragRes.Success {
 		fmt.Println("WORKS (High Priority)")
 		r.IPFragWorks = true
 	} else {
@@ -35,26 +33,56 @@ func RunScout(ctx context.Context, pool *container.WorkerPool, group string) mod
 		r.IPFragWorks = false
 	}
 
-	// 2. Check BadSum
-	fmt.Print("    [?] Probing BadSum (fake+badsum)... ")
+	// 2. Fingerprint matrix: each fooling/split/window technique on its own
+	probeFingerprint(ctx, pool, groups, &r)
+	for _, e := range r.Fingerprint {
+		if e.Technique == model.TechBadSum {
+			r.BadSumWorks = e.Effective()
+		}
+	}
+
+	// 3. Hop distance to the DPI
+	probeTTL(ctx, pool, groups, &r)
+
+	// 4. TCP 16-20KB freeze detector (no desync, nfqws passes packets unchanged)
+	probeFreeze(ctx, pool, groups, &r)
+
+	return r
+}
+
+// probeFreeze measures where transfers of the general targets stall without any bypass
+func probeFreeze(ctx context.Context, pool *container.WorkerPool, groups Groups, r *model.ReconReport) {
+	fmt.Print("    [?] Probing TCP 16-20KB freeze (general targets, no desync)... ")
 
-	badsumReq := model.WorkerRequest{
-		StrategyArgs: "--dpi-desync=fake --dpi-desync-fooling=badsum",
-		TargetGroup:  group,
+	req, ok := groups.request(GroupFreeze, "")
+	if !ok {
+		fmt.Printf("SKIPPED (no %s targets)\n", GroupFreeze)
+		return
 	}
+	req.CheckMode = verifier.ModeFreeze
+	res, err := pool.Exec(ctx, req)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return
+	}
+
+	diag := verifier.DiagnoseFreeze(res.Targets)
+	r.FreezeCutoff = diag.Detected()
+	r.FreezeInWindow = diag.InWindow
+	r.FreezeTotal = diag.Total
 
-	badsumRes, err := pool.Exec(ctx, badsumReq)
-	if err == nil && badsumRes.Success {
-		fmt.Println("WORKS (Will Boost)")
-		r.BadSumWorks = true
+	if r.FreezeCutoff {
+		fmt.Printf("DETECTED (%d/%d targets freeze at 16-20KB, general phase is needed)\n", diag.InWindow, diag.Total)
 	} else {
-		if err != nil {
-			fmt.Printf("ERROR: %v\n", err)
-		} else {
-			fmt.Println("NO EFFECT/FAILED (Standard probability)")
-		}
-		r.BadSumWorks = false
+		fmt.Printf("NOT DETECTED (%d/%d frozen, %d in 16-20KB window, general phase can likely be skipped)\n", diag.Frozen, diag.Total, diag.InWindow)
 	}
 
-	return r
+	for _, t := range res.Targets {
+		switch {
+		case t.Cutoff:
+			fmt.Printf("        [16-20KB] froze at %6dB  %s\n", t.Bytes, t.URL)
+		case t.Class == model.ClassStalled:
+			fmt.Printf("        [stalled] froze at %6dB  %s\n", t.Bytes, t.URL)
+		}
+	}
 }
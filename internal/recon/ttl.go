@@ -0,0 +1,82 @@
+package recon
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"prikop/internal/container"
+	"prikop/internal/model"
+)
+
+// MaxProbeTTL — дальше этого числа хопов DPI провайдера не ищем
+const MaxProbeTTL = 12
+
+// probeTTL sends fakes with increasing TTL: below the DPI distance the fake dies
+// before the DPI and changes nothing, at or past it the DPI sees the fake.
+// The smallest TTL that bypasses is the hop distance to the DPI; "bypasses" means
+// more targets pass than in a control run without any strategy.
+func probeTTL(ctx context.Context, pool *container.WorkerPool, groups Groups, r *model.ReconReport) {
+	fmt.Printf("    [?] Probing TTL distance to DPI (fake, ttl 1-%d)... ", MaxProbeTTL)
+
+	controlReq, ok := groups.request(GroupTCP, "")
+	if !ok {
+		fmt.Printf("SKIPPED (no %s targets)\n", GroupTCP)
+		return
+	}
+
+	control := 0
+	var controlErr string
+	passed := make([]int, MaxProbeTTL+1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		res, err := pool.Exec(ctx, controlReq)
+		switch {
+		case err != nil:
+			controlErr = err.Error()
+		case res.Error != "":
+			controlErr = res.Error
+		default:
+			control = res.SuccessCount
+		}
+	}()
+	for ttl := 1; ttl <= MaxProbeTTL; ttl++ {
+		wg.Add(1)
+		go func(ttl int) {
+			defer wg.Done()
+			req, _ := groups.request(GroupTCP, fmt.Sprintf("--dpi-desync=fake --dpi-desync-ttl=%d", ttl))
+			res, err := pool.Exec(ctx, req)
+			if err == nil && res.Error == "" {
+				passed[ttl] = res.SuccessCount
+			}
+		}(ttl)
+	}
+	wg.Wait()
+
+	// Без контроля любая проба "обходит" DPI, и дистанция была бы ложной
+	if controlErr != "" {
+		fmt.Printf("ERROR: control run: %s\n", controlErr)
+		return
+	}
+
+	works := make([]bool, MaxProbeTTL+1)
+	for ttl := 1; ttl <= MaxProbeTTL; ttl++ {
+		works[ttl] = passed[ttl] > control
+	}
+
+	for ttl := 1; ttl <= MaxProbeTTL; ttl++ {
+		if works[ttl] {
+			r.TTLDistance = ttl
+			break
+		}
+	}
+
+	if r.TTLDistance == 0 {
+		fmt.Println("NOT FOUND (fakes do not bypass at any TTL, TTL stays random)")
+		return
+	}
+	fmt.Printf("%d hops (TTL will focus around it)\n", r.TTLDistance)
+}
@@ -0,0 +1,86 @@
+package verifier
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// BlockScanBytes — сколько начальных байт тела просматривается на маркеры заглушки
+const BlockScanBytes = 8 * 1024
+
+// Хосты заглушек провайдеров, на которые DPI перенаправляет заблокированные ресурсы
+var blockHosts = []string{
+	"warning.rt.ru",
+	"blackhole.beeline.ru",
+	"blocked.mts.ru",
+	"block.dom.ru",
+	"blocked.netbynet.ru",
+	"zapret.tele2.ru",
+	"fz139.ttk.ru",
+	"eais.rkn.gov.ru",
+	"blocklist.rkn.gov.ru",
+}
+
+// Подписи в теле типовых страниц блокировки (сравнение без учета регистра)
+var blockMarkers = []string{
+	"eais.rkn.gov.ru",
+	"blocklist.rkn.gov.ru",
+	"zapret-info.gov.ru",
+	"роскомнадзор",
+	"единый реестр",
+	"доступ к запрашиваемому ресурсу ограничен",
+	"доступ к ресурсу ограничен",
+	"ресурс заблокирован",
+	"149-фз",
+}
+
+// isBlockHost reports whether host is a known ISP stub or one of its subdomains
+func isBlockHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, h := range blockHosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameSite treats a host, its subdomains and its parent domain as one site
+// (example.com -> www.example.com is a normal redirect, not a stub)
+func sameSite(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	return a == b || strings.HasSuffix(a, "."+b) || strings.HasSuffix(b, "."+a)
+}
+
+// redirectBlocked checks where a plain HTTP response redirects:
+// a stub host or a foreign site means the request never reached the origin.
+func redirectBlocked(origin, loc *url.URL) string {
+	if loc == nil {
+		return ""
+	}
+	if isBlockHost(loc.Hostname()) {
+		return fmt.Sprintf("redirect to block page %s", loc.Host)
+	}
+	if loc.Hostname() != "" && !sameSite(origin.Hostname(), loc.Hostname()) {
+		return fmt.Sprintf("redirect to foreign host %s", loc.Host)
+	}
+	return ""
+}
+
+// blockMarker returns the block-page signature found in the body head, "" if none
+func blockMarker(head []byte) string {
+	lower := bytes.ToLower(head)
+	for _, m := range blockMarkers {
+		if bytes.Contains(lower, []byte(m)) {
+			return m
+		}
+	}
+	for _, h := range blockHosts {
+		if bytes.Contains(lower, []byte(h)) {
+			return h
+		}
+	}
+	return ""
+}
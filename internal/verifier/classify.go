@@ -0,0 +1,160 @@
+package verifier
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http/httptrace"
+	"prikop/internal/model"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// traceState records how far a TCP request progressed before it failed
+// and when each stage finished (milliseconds since start)
+type traceState struct {
+	start     time.Time
+	quic      bool // http3 does not support httptrace, only ttfb is recorded
+	connected atomic.Bool
+	tlsDone   atomic.Bool
+	firstByte atomic.Bool
+
+	connectStart, connectMs, tlsStart, tlsMs, ttfbMs atomic.Int64
+}
+
+func newTraceState() *traceState {
+	return &traceState{start: time.Now()}
+}
+
+func (st *traceState) since() int64 {
+	return time.Since(st.start).Milliseconds()
+}
+
+func (st *traceState) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		ConnectStart: func(_, _ string) {
+			st.connectStart.Store(st.since())
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				st.connected.Store(true)
+				st.connectMs.Store(st.since() - st.connectStart.Load())
+			}
+		},
+		TLSHandshakeStart: func() {
+			st.tlsStart.Store(st.since())
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				st.tlsDone.Store(true)
+				st.tlsMs.Store(st.since() - st.tlsStart.Load())
+			}
+		},
+		GotFirstResponseByte: func() {
+			st.firstByte.Store(true)
+			st.ttfbMs.Store(st.since())
+		},
+	}
+}
+
+// fill copies stage timings into the target result
+func (st *traceState) fill(tr *model.TargetResult) {
+	tr.ConnectMs = st.connectMs.Load()
+	tr.TLSMs = st.tlsMs.Load()
+	tr.TTFBMs = st.ttfbMs.Load()
+}
+
+// classify maps a check error to a failure class using the request progress.
+// For QUIC the progress is unknown, quic-go error types are used instead.
+func classify(err error, st *traceState, bytes int) string {
+	var dnsErr *net.DNSError
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return model.ClassCancelled
+	case errors.As(err, &dnsErr):
+		return model.ClassDNS
+	case bytes > 0:
+		return model.ClassStalled
+	case isTLSAlert(err):
+		return model.ClassTLSAlert
+	}
+
+	if st.quic {
+		return classifyQUIC(err)
+	}
+
+	switch {
+	case st.firstByte.Load():
+		return model.ClassStalled
+	case !st.connected.Load():
+		return model.ClassDial
+	case isReset(err) && !st.tlsDone.Load():
+		return model.ClassResetHandshake
+	case isReset(err):
+		return model.ClassReset
+	case isTimeout(err) && !st.tlsDone.Load():
+		return model.ClassHandshakeTimeout
+	case isTimeout(err):
+		return model.ClassTimeout
+	}
+	return model.ClassError
+}
+
+func classifyQUIC(err error) string {
+	var (
+		hsTimeout   *quic.HandshakeTimeoutError
+		idleTimeout *quic.IdleTimeoutError
+		reset       *quic.StatelessResetError
+		transport   *quic.TransportError
+	)
+
+	switch {
+	case errors.As(err, &hsTimeout):
+		return model.ClassHandshakeTimeout
+	case errors.As(err, &idleTimeout):
+		return model.ClassTimeout
+	case errors.As(err, &reset):
+		return model.ClassReset
+	case errors.As(err, &transport) && transport.ErrorCode.IsCryptoError():
+		return model.ClassTLSAlert
+	case isTimeout(err):
+		return model.ClassHandshakeTimeout
+	}
+	return model.ClassError
+}
+
+// isReset matches RST as well as a FIN injected in place of the server reply
+func isReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func isTLSAlert(err error) bool {
+	var alert tls.AlertError
+	if errors.As(err, &alert) {
+		return true
+	}
+	// Alerts received over TCP are wrapped in an unexported type
+	return strings.Contains(err.Error(), "remote error: tls:")
+}
+
+// DeadClass reports failures seen without any bypass that DPI does not cause:
+// the name does not resolve or nothing listens. Such targets are broken by
+// themselves, no strategy fixes them. A short body, an error status or wrong
+// content is what a DPI stub or an injected FIN looks like, so those stay blocked.
+func DeadClass(class string) bool {
+	switch class {
+	case model.ClassDNS, model.ClassDial:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package verifier
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/quic-go/quic-go"
+
+	"prikop/internal/model"
+)
+
+func TestClassify(t *testing.T) {
+	const (
+		none = iota
+		connected
+		tlsDone
+		firstByte
+	)
+	cases := []struct {
+		name  string
+		err   error
+		stage int
+		bytes int
+		want  string
+	}{
+		{"cancelled", context.Canceled, tlsDone, 0, model.ClassCancelled},
+		{"dns", &net.DNSError{Err: "no such host", Name: "example.com"}, none, 0, model.ClassDNS},
+		{"body cut", io.ErrUnexpectedEOF, tlsDone, 4096, model.ClassStalled},
+		{"tls alert", tls.AlertError(40), connected, 0, model.ClassTLSAlert},
+		{"wrapped tls alert", errors.New("remote error: tls: handshake failure"), connected, 0, model.ClassTLSAlert},
+		{"headers then silence", context.DeadlineExceeded, firstByte, 0, model.ClassStalled},
+		{"refused", syscall.ECONNREFUSED, none, 0, model.ClassDial},
+		{"rst in handshake", syscall.ECONNRESET, connected, 0, model.ClassResetHandshake},
+		{"fin in handshake", io.EOF, connected, 0, model.ClassResetHandshake},
+		{"rst after handshake", fmt.Errorf("read: %w", syscall.ECONNRESET), tlsDone, 0, model.ClassReset},
+		{"handshake timeout", context.DeadlineExceeded, connected, 0, model.ClassHandshakeTimeout},
+		{"no reply", context.DeadlineExceeded, tlsDone, 0, model.ClassTimeout},
+		{"other", errors.New("malformed response"), tlsDone, 0, model.ClassError},
+	}
+	for _, c := range cases {
+		st := newTraceState()
+		st.connected.Store(c.stage >= connected)
+		st.tlsDone.Store(c.stage >= tlsDone)
+		st.firstByte.Store(c.stage >= firstByte)
+		if got := classify(c.err, st, c.bytes); got != c.want {
+			t.Errorf("%s: class %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestClassifyQUIC(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"handshake timeout", &quic.HandshakeTimeoutError{}, model.ClassHandshakeTimeout},
+		{"idle timeout", &quic.IdleTimeoutError{}, model.ClassTimeout},
+		{"stateless reset", &quic.StatelessResetError{}, model.ClassReset},
+		{"crypto error", &quic.TransportError{ErrorCode: quic.TransportErrorCode(0x128)}, model.ClassTLSAlert},
+		{"deadline", context.DeadlineExceeded, model.ClassHandshakeTimeout},
+		{"other", errors.New("malformed response"), model.ClassError},
+	}
+	for _, c := range cases {
+		st := newTraceState()
+		st.quic = true
+		if got := classify(c.err, st, 0); got != c.want {
+			t.Errorf("%s: class %q, want %q", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package verifier
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"prikop/internal/model"
+)
+
+// RegexScanBytes — сколько начальных байт тела читается для BodyRegex
+const RegexScanBytes = 64 * 1024
+
+// headLimit returns how much of the body start is kept for content checks
+func headLimit(tgt Target) int {
+	limit := max(BlockScanBytes, tgt.HeadBytes)
+	if tgt.BodyRegex != "" {
+		limit = max(limit, RegexScanBytes)
+	}
+	return limit
+}
+
+// certMismatch checks the leaf certificate against the target pins.
+// A mismatch under InsecureSkipVerify is the only sign of a TLS substitution.
+func certMismatch(tgt Target, state *tls.ConnectionState) string {
+	if tgt.SPKISHA256 == "" && tgt.Issuer == "" {
+		return ""
+	}
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return "no peer certificate"
+	}
+	leaf := state.PeerCertificates[0]
+
+	if tgt.SPKISHA256 != "" {
+		sum := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+		if got := hex.EncodeToString(sum[:]); !strings.HasPrefix(got, strings.ToLower(tgt.SPKISHA256)) {
+			return fmt.Sprintf("spki %s, expected %s (issuer %s)", got[:16], tgt.SPKISHA256, leaf.Issuer)
+		}
+	}
+	if tgt.Issuer != "" && !strings.Contains(strings.ToLower(leaf.Issuer.String()), strings.ToLower(tgt.Issuer)) {
+		return fmt.Sprintf("issuer %q, expected %q", leaf.Issuer.String(), tgt.Issuer)
+	}
+	return ""
+}
+
+// headMismatch validates the beginning of the body: stub markers first,
+// then the expected head hash and body regex. Returns the failure class and reason.
+func headMismatch(tgt Target, head []byte) (string, string) {
+	if marker := blockMarker(head); marker != "" {
+		return model.ClassBlocked, "block page: " + marker
+	}
+
+	if tgt.HeadSHA256 != "" && tgt.HeadBytes > 0 {
+		if len(head) < tgt.HeadBytes {
+			return model.ClassContent, fmt.Sprintf("head of %d bytes, expected %d", len(head), tgt.HeadBytes)
+		}
+		sum := sha256.Sum256(head[:tgt.HeadBytes])
+		if got := hex.EncodeToString(sum[:]); !strings.HasPrefix(got, strings.ToLower(tgt.HeadSHA256)) {
+			return model.ClassContent, fmt.Sprintf("head sha256 %s, expected %s", got[:16], tgt.HeadSHA256)
+		}
+	}
+
+	if tgt.BodyRegex != "" {
+		re, err := bodyRegex(tgt.BodyRegex)
+		if err != nil {
+			return model.ClassError, err.Error()
+		}
+		if !re.Match(head) {
+			return model.ClassContent, fmt.Sprintf("body does not match %q", tgt.BodyRegex)
+		}
+	}
+	return "", ""
+}
+
+// bodyRegexps caches compiled BodyRegex patterns: targets are loaded once,
+// but checked on every evaluation
+var bodyRegexps sync.Map
+
+// bodyRegex returns the compiled pattern, compiling it on first use
+func bodyRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := bodyRegexps.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	bodyRegexps.Store(pattern, re)
+	return re, nil
+}
+
+// compileTargets compiles the body patterns of the targets when they are loaded
+func compileTargets(targets []Target) error {
+	for _, t := range targets {
+		if t.BodyRegex == "" {
+			continue
+		}
+		if _, err := bodyRegex(t.BodyRegex); err != nil {
+			return fmt.Errorf("%s: body_regex: %w", t.URL, err)
+		}
+	}
+	return nil
+}
+
+// contentMismatch compares the downloaded body with the expected size and hash
+func contentMismatch(tgt Target, size int, sum string) string {
+	if tgt.Size > 0 && int64(size) != tgt.Size {
+		return fmt.Sprintf("size %d, expected %d", size, tgt.Size)
+	}
+	// A hex prefix is enough to pin the content
+	if tgt.SHA256 != "" && !strings.HasPrefix(sum, strings.ToLower(tgt.SHA256)) {
+		return fmt.Sprintf("sha256 %s, expected %s", sum[:16], tgt.SHA256)
+	}
+	return ""
+}
@@ -1,32 +0,0 @@
-package verifier
-
-const DefaultThreshold = 64 * 1024
-
-var GeneralTargets = []Target{
-	{URL: "https://img.wzstats.gg/cleaver/gunFullDisplay", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://genshin.jmp.blue/characters/all#", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://api.frankfurter.dev/v1/2000-01-01..2002-12-31", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://www.bigcartel.com/", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://genderize.io/", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://j.dejure.org/jcg/doctrine/doctrine_banner.webp", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://accesorioscelular.com/tienda/css/plugins.css", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://251b5cd9.nip.io/1MB.bin", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://nioges.com/libs/fontawesome/webfonts/fa-solid-900.woff2", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://5fd8bdae.nip.io/1MB.bin", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://5fd8bca5.nip.io/1MB.bin", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://eu.api.ovh.com/console/rapidoc-min.js", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://ovh.sfx.ovh/10M.bin", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://oracle.sfx.ovh/10M.bin", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://www.getscope.com/assets/fonts/fa-solid-900.woff2", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://corp.kaltura.com/wp-content/cache/min/1/wp-content/themes/airfleet/dist/styles/theme.css", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://api.usercentrics.eu/gvl/v3/en.json", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://www.jetblue.com/footer/footer-element-es2015.js", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://ssl.p.jwpcdn.com/player/v/8.40.5/bidding.js", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://www.roxio.com/static/roxio/images/products/creator/nxt9/call-action-footer-bg.jpg", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://media-assets.stryker.com/is/image/stryker/gateway_1?$max_width_1410$", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://cdn.eso.org/images/banner1920/eso2520a.jpg", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://xdmarineshop.gr/index.php?route=index", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://www.velivole.fr/img/header.jpg", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://cdn.xuansiwei.com/common/lib/font-awesome/4.7.0/fontawesome-webfont.woff2?v=4.7.0", Threshold: DefaultThreshold, IgnoreStatus: true},
-	{URL: "https://scontent-cdg4-2.cdninstagram.com", Threshold: DefaultThreshold, IgnoreStatus: true},
-}
@@ -0,0 +1,118 @@
+package verifier
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"prikop/internal/model"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ModeDNS compares system resolver answers with DoH for every target, without checking the target itself
+const ModeDNS = "dns"
+
+// TargetHost returns the host name a target resolves, "" for IP literals
+func TargetHost(t Target) string {
+	host := t.URL
+	if IsUDP(t.Proto) {
+		host, _, _ = net.SplitHostPort(t.URL)
+	} else if u, err := url.Parse(t.URL); err == nil {
+		host = u.Hostname()
+	}
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+	return strings.ToLower(host)
+}
+
+// SNIProbeTimeout bounds the TLS probe of a disjoint system answer
+const SNIProbeTimeout = 3 * time.Second
+
+// checkDNS resolves the target host with the system resolver and with DoH.
+// Passed=false with ClassDNS means the system answer is poisoned: no strategy can fix it.
+// Answers that merely differ (CDN geo-balancing) pass with a note in Error, unless
+// the system IP cannot serve the host over TLS while a DoH IP can: that is a stub server.
+func checkDNS(ctx context.Context, tgt Target) model.TargetResult {
+	host := TargetHost(tgt)
+	if host == "" || tgt.IP != "" {
+		return model.TargetResult{Passed: true}
+	}
+
+	probeCtx := ctx
+	ctx, cancel := context.WithTimeout(ctx, HardTimeout)
+	defer cancel()
+
+	doh, err := ResolverFor(ResolverDoH)
+	if err != nil {
+		return model.TargetResult{Class: model.ClassError, Error: err.Error()}
+	}
+	want, dohErr := doh.LookupIP(ctx, host)
+	if dohErr != nil {
+		// Nothing to compare with, the system answer is trusted
+		return model.TargetResult{Passed: true, Error: "doh: " + dohErr.Error()}
+	}
+
+	addrs, sysErr := net.DefaultResolver.LookupHost(ctx, host)
+	var got []string
+	for _, a := range addrs {
+		if ip := net.ParseIP(a); ip != nil && ip.To4() != nil {
+			got = append(got, a)
+		}
+	}
+
+	if msg := dnsPoisoned(got, sysErr, want); msg != "" {
+		return model.TargetResult{Class: model.ClassDNS, Error: msg}
+	}
+	tr := model.TargetResult{Passed: true}
+	if !slices.ContainsFunc(got, func(ip string) bool { return slices.Contains(want, ip) }) {
+		if !IsUDP(tgt.Proto) && len(want) > 0 && stubServer(probeCtx, host, got[0], want[0]) {
+			return model.TargetResult{Class: model.ClassDNS, Error: fmt.Sprintf("system resolver returns %s without a valid certificate for %s, doh %v", got[0], host, want)}
+		}
+		tr.Error = fmt.Sprintf("answers differ: system %v, doh %v", got, want)
+	}
+	return tr
+}
+
+// stubServer probes both answers with a verified TLS handshake for host. DPI breaks
+// both handshakes alike (it sees the same SNI), so only "DoH IP works, system IP
+// does not" points to a substituted address.
+func stubServer(ctx context.Context, host, sysIP, dohIP string) bool {
+	ctx, cancel := context.WithTimeout(ctx, SNIProbeTimeout)
+	defer cancel()
+
+	dohOK := make(chan bool, 1)
+	go func() { dohOK <- tlsServes(ctx, dohIP, host) }()
+	sysOK := tlsServes(ctx, sysIP, host)
+	return !sysOK && <-dohOK
+}
+
+// tlsServes reports whether ip completes a TLS handshake with a certificate valid for host
+func tlsServes(ctx context.Context, ip, host string) bool {
+	d := tls.Dialer{Config: &tls.Config{ServerName: host}}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip, "443"))
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// dnsPoisoned decides whether the system answer was substituted, given a trusted DoH answer
+func dnsPoisoned(got []string, sysErr error, want []string) string {
+	if sysErr != nil || len(got) == 0 {
+		return fmt.Sprintf("system resolver fails (%v), doh %v", sysErr, want)
+	}
+	for _, a := range got {
+		ip := net.ParseIP(a)
+		if ip.IsPrivate() || ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() {
+			if !slices.Contains(want, a) {
+				return fmt.Sprintf("system resolver returns %s, doh %v", a, want)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package verifier
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDNSPoisoned(t *testing.T) {
+	doh := []string{"142.250.74.78", "142.250.74.110"}
+	cases := []struct {
+		name   string
+		got    []string
+		sysErr error
+		want   bool
+	}{
+		{"same answer", []string{"142.250.74.78"}, nil, false},
+		// CDN geo-balancing: another public address is not poisoning by itself
+		{"other public address", []string{"173.194.222.100"}, nil, false},
+		{"resolver error", nil, errors.New("no such host"), true},
+		{"empty answer", nil, nil, true},
+		{"private stub", []string{"10.10.34.34"}, nil, true},
+		{"loopback", []string{"127.0.0.1"}, nil, true},
+		{"unspecified", []string{"0.0.0.0"}, nil, true},
+		{"link-local", []string{"169.254.1.1"}, nil, true},
+		{"stub among real addresses", []string{"142.250.74.78", "192.168.0.1"}, nil, true},
+	}
+	for _, c := range cases {
+		if got := dnsPoisoned(c.got, c.sysErr, doh) != ""; got != c.want {
+			t.Errorf("%s: poisoned %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	// A private address DoH returns too is the real one (internal hosts)
+	if msg := dnsPoisoned([]string{"10.0.0.5"}, nil, []string{"10.0.0.5"}); msg != "" {
+		t.Errorf("private address confirmed by doh: %s", msg)
+	}
+}
+
+func TestTargetHost(t *testing.T) {
+	cases := []struct {
+		tgt  Target
+		want string
+	}{
+		{Target{URL: "https://WWW.Google.com/generate_204"}, "www.google.com"},
+		{Target{URL: "http://example.com:8080/"}, "example.com"},
+		{Target{URL: "https://1.1.1.1/"}, ""},
+		{Target{URL: "stun.l.google.com:19302", Proto: ProtoSTUN}, "stun.l.google.com"},
+		{Target{URL: "192.0.2.1:51820", Proto: ProtoUDP}, ""},
+	}
+	for _, c := range cases {
+		if got := TargetHost(c.tgt); got != c.want {
+			t.Errorf("TargetHost(%s) = %q, want %q", c.tgt.URL, got, c.want)
+		}
+	}
+}
@@ -2,176 +2,373 @@ package verifier
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
-	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
-	"math/rand"
 	"net"
 	"net/http"
-	"strings"
+	"net/http/httptrace"
+	"prikop/internal/model"
 	"sync"
 	"time"
 
+	"github.com/quic-go/quic-go"
 	"github.com/quic-go/quic-go/http3"
 )
 
 const (
 	HardTimeout = 5 * time.Second
-	UserAgent   = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	// ThroughputWindow — сколько качаем крупную цель после порога для замера скорости
+	ThroughputWindow = 2 * time.Second
+	// MaxContentRead — предел чтения тела при сверке размера/хеша
+	MaxContentRead = 16 * 1024 * 1024
+	UserAgent      = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
 )
 
 var (
 	tcpClient  *http.Client
 	quicClient *http.Client
+	// httpClient не следует редиректам: по ответу на http:// видно, кто ответил — сайт или заглушка
+	httpClient *http.Client
 	initOnce   sync.Once
 )
 
 func initClients() {
 	// Transports with InsecureSkipVerify (DPI bypass check, not security check)
+	dialer := &net.Dialer{Timeout: HardTimeout}
+
 	tcpTransport := &http.Transport{
 		TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
 		DisableKeepAlives:     true, // Force new connection for each check to trigger DPI
 		TLSHandshakeTimeout:   HardTimeout,
 		ResponseHeaderTimeout: HardTimeout,
-		DialContext: (&net.Dialer{
-			Timeout: HardTimeout,
-		}).DialContext,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			target, err := dialAddr(ctx, addr)
+			if err != nil {
+				return nil, err
+			}
+			return dialer.DialContext(ctx, network, target)
+		},
 		ForceAttemptHTTP2: true,
 	}
 
 	quicTransport := &http3.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+			target, err := dialAddr(ctx, addr)
+			if err != nil {
+				return nil, err
+			}
+			return quic.DialAddrEarly(ctx, target, tlsCfg, cfg)
+		},
 	}
 
 	tcpClient = &http.Client{Timeout: HardTimeout, Transport: tcpTransport}
 	quicClient = &http.Client{Timeout: HardTimeout, Transport: quicTransport}
+	httpClient = &http.Client{
+		Timeout:   HardTimeout,
+		Transport: tcpTransport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
 }
 
 // ExecuteChecks runs parallel checks against the provided targets.
-func ExecuteChecks(ctx context.Context, targets []Target) CheckResult {
+// opts.Report (optional) is called as soon as each target finishes.
+func ExecuteChecks(ctx context.Context, targets []Target, opts RunOptions) CheckResult {
 	initOnce.Do(initClients)
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ctx = withResolver(ctx, opts.Resolver)
+
 	var wg sync.WaitGroup
 	var mu sync.Mutex
-	var passed []string
-	var failed []string
+	var res CheckResult
+	// reachable — вес, который еще можно набрать
+	reachable := TotalWeight(targets)
 
 	for _, t := range targets {
 		wg.Add(1)
 
 		go func(tgt Target) {
 			defer wg.Done()
-			success := false
-
-			defer func() {
-				mu.Lock()
-				if success {
-					passed = append(passed, tgt.URL)
-				} else {
-					failed = append(failed, tgt.URL)
-				}
-				mu.Unlock()
-			}()
-
-			if tgt.Proto == "stun" {
-				if checkSTUN(ctx, tgt.URL) {
-					success = true
-				}
-				return
-			}
 
-			// Use global clients
-			cli := tcpClient
-			if tgt.Proto == "quic" {
-				cli = quicClient
+			start := time.Now()
+			var tr model.TargetResult
+			switch opts.Mode {
+			case ModeFreeze:
+				tr = checkFreeze(ctx, tgt)
+			case ModeDNS:
+				tr = checkDNS(ctx, tgt)
+			default:
+				tr = checkTarget(ctx, tgt, opts.MinThroughput)
 			}
+			tr.URL = tgt.URL
+			tr.Weight = tgt.EffectiveWeight()
+			tr.Required = tgt.Required
+			tr.LatencyMs = time.Since(start).Milliseconds()
 
-			reqCtx, cancel := context.WithTimeout(ctx, HardTimeout)
-			defer cancel()
-
-			req, err := http.NewRequestWithContext(reqCtx, "GET", tgt.URL, nil)
-			if err != nil {
-				return
+			mu.Lock()
+			res.add(tr)
+			if !tr.Passed {
+				reachable -= tr.Weight
 			}
-
-			req.Header.Set("User-Agent", UserAgent)
-
-			resp, err := cli.Do(req)
-			if err != nil {
-				return
-			}
-			defer resp.Body.Close()
-
-			if !tgt.IgnoreStatus && (resp.StatusCode < 200 || resp.StatusCode >= 400) {
-				return
+			// Racing: the required weight is out of reach, stop the rest
+			if opts.MinWeight > 0 && !res.Raced && reachable < opts.MinWeight {
+				res.Raced = true
+				cancel()
 			}
+			mu.Unlock()
 
-			// Efficient body read without full allocation if threshold is small
-			buf := make([]byte, 4096)
-			readTotal := 0
-
-			for readTotal < tgt.Threshold {
-				n, err := resp.Body.Read(buf)
-				if n > 0 {
-					readTotal += n
-				}
-				if err != nil {
-					if err == io.EOF {
-						break
-					}
-					return
-				}
-			}
-
-			if readTotal >= tgt.Threshold {
-				success = true
+			if opts.Report != nil {
+				opts.Report(tr)
 			}
 		}(t)
 	}
 
 	wg.Wait()
 
-	return CheckResult{
-		Success:      len(passed) > 0,
-		SuccessCount: len(passed),
-		TotalCount:   len(targets),
-		PassedUrls:   passed,
-		FailedUrls:   failed,
+	res.TotalCount = len(targets)
+	res.TotalWeight = TotalWeight(targets)
+	res.Success = res.SuccessCount > 0 && !res.requiredFailed()
+	return res
+}
+
+// add accumulates one target result (caller holds the lock)
+func (r *CheckResult) add(tr model.TargetResult) {
+	r.Targets = append(r.Targets, tr)
+	if tr.Passed {
+		r.SuccessCount++
+		r.Weight += tr.Weight
+		r.PassedUrls = append(r.PassedUrls, tr.URL)
+		return
 	}
+	r.FailedUrls = append(r.FailedUrls, tr.URL)
+	if tr.Class == model.ClassTimeout {
+		r.Timeouts++
+	}
+}
+
+func (r *CheckResult) requiredFailed() bool {
+	for _, t := range r.Targets {
+		if t.Required && !t.Passed {
+			return true
+		}
+	}
+	return false
+}
+
+// TotalWeight sums effective weights of the targets
+func TotalWeight(targets []Target) float64 {
+	total := 0.0
+	for _, t := range targets {
+		total += t.EffectiveWeight()
+	}
+	return total
 }
 
-func checkSTUN(ctx context.Context, address string) bool {
-	address = strings.TrimPrefix(address, "https://")
-	address = strings.TrimPrefix(address, "http://")
+// checkTarget performs a single check and classifies the outcome
+func checkTarget(ctx context.Context, tgt Target, minThroughput int64) model.TargetResult {
+	switch tgt.Proto {
+	case ProtoSTUN:
+		return checkSTUN(ctx, tgt)
+	case ProtoDiscordIP:
+		return checkDiscordIP(ctx, tgt)
+	case ProtoUDP:
+		return checkUDP(ctx, tgt)
+	}
 
-	d := net.Dialer{Timeout: 3 * time.Second}
-	conn, err := d.DialContext(ctx, "udp", address)
+	// Use global clients
+	cli := tcpClient
+	st := newTraceState()
+	if tgt.Proto == "quic" {
+		cli = quicClient
+		st.quic = true
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, HardTimeout)
+	defer cancel()
+	reqCtx = withPinnedIP(reqCtx, tgt.IP)
+	if !st.quic {
+		reqCtx = httptrace.WithClientTrace(reqCtx, st.clientTrace())
+	}
+
+	req, err := http.NewRequestWithContext(reqCtx, "GET", tgt.URL, nil)
 	if err != nil {
-		return false
+		return failure(err, st, 0)
 	}
-	defer conn.Close()
 
-	req := make([]byte, 20)
-	binary.BigEndian.PutUint16(req[0:2], 0x0001)     // Type
-	binary.BigEndian.PutUint16(req[2:4], 0x0000)     // Length
-	binary.BigEndian.PutUint32(req[4:8], 0x2112A442) // Magic Cookie
-	rand.Read(req[8:20])                             // Transaction ID
+	req.Header.Set("User-Agent", UserAgent)
 
-	if _, err := conn.Write(req); err != nil {
-		return false
+	plainHTTP := req.URL.Scheme == "http" && !st.quic
+	if plainHTTP {
+		cli = httpClient
 	}
 
-	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
-	resp := make([]byte, 1024)
-	n, err := conn.Read(resp)
+	resp, err := cli.Do(req)
 	if err != nil {
-		return false
+		return failure(err, st, 0)
 	}
+	defer resp.Body.Close()
 
-	if n < 20 {
-		return false
+	// http3 has no httptrace: headers arrival is the best TTFB we get
+	if st.quic {
+		st.ttfbMs.Store(st.since())
+	}
+
+	// The classic HTTP stub: 302 to the provider's warning page.
+	// A redirect within the site comes from the origin and needs no body.
+	originRedirect := false
+	if plainHTTP && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		loc, _ := resp.Location()
+		if msg := redirectBlocked(req.URL, loc); msg != "" {
+			tr := model.TargetResult{Status: resp.StatusCode, Class: model.ClassBlocked, Error: msg}
+			st.fill(&tr)
+			return tr
+		}
+		originRedirect = loc != nil
 	}
 
-	msgType := binary.BigEndian.Uint16(resp[0:2])
-	return msgType == 0x0101 || msgType == 0x0111
+	// Stub served under a substituted certificate
+	if msg := certMismatch(tgt, resp.TLS); msg != "" {
+		tr := model.TargetResult{Status: resp.StatusCode, Class: model.ClassMITM, Error: msg}
+		st.fill(&tr)
+		return tr
+	}
+
+	if !tgt.IgnoreStatus && (resp.StatusCode < 200 || resp.StatusCode >= 400) {
+		tr := model.TargetResult{Status: resp.StatusCode, Class: model.ClassStatus, Error: resp.Status}
+		st.fill(&tr)
+		return tr
+	}
+
+	// Large targets keep reading past the threshold to measure sustained throughput,
+	// targets with expected content are read to the end
+	limit := max(tgt.Threshold, tgt.ReadLimit)
+	verifyContent := tgt.SHA256 != "" || tgt.Size > 0
+	if verifyContent {
+		limit = max(limit, int(tgt.Size), MaxContentRead)
+	}
+	// need — сколько прочитать до вердикта: маркер заглушки может стоять
+	// где угодно в начале тела, а не только в первом прочитанном куске
+	headMax := headLimit(tgt)
+	need := max(tgt.Threshold, headMax)
+	limit = max(limit, need)
+
+	// Efficient body read without full allocation if threshold is small
+	buf := make([]byte, 4096)
+	readTotal := 0
+	bodyStart := time.Now()
+	hasher := sha256.New()
+	var head []byte // начало тела для маркеров заглушки и проверок содержимого
+
+	for readTotal < limit {
+		if !verifyContent && readTotal >= need && time.Since(bodyStart) > ThroughputWindow {
+			break
+		}
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			readTotal += n
+			if verifyContent {
+				hasher.Write(buf[:n])
+			}
+			if len(head) < headMax {
+				head = append(head, buf[:min(n, headMax-len(head))]...)
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			if !verifyContent && readTotal >= need && tgt.ReadLimit > 0 {
+				// Threshold reached, only the throughput measurement was cut
+				break
+			}
+			tr := failure(err, st, readTotal)
+			tr.Status = resp.StatusCode
+			return tr
+		}
+	}
+
+	tr := model.TargetResult{Bytes: readTotal, Status: resp.StatusCode}
+	st.fill(&tr)
+
+	if class, msg := headMismatch(tgt, head); class != "" {
+		tr.Class = class
+		tr.Error = msg
+		return tr
+	}
+
+	if readTotal < tgt.Threshold && !originRedirect {
+		tr.Class = model.ClassShort
+		return tr
+	}
+
+	if verifyContent {
+		if msg := contentMismatch(tgt, readTotal, hex.EncodeToString(hasher.Sum(nil))); msg != "" {
+			tr.Class = model.ClassContent
+			tr.Error = msg
+			return tr
+		}
+	}
+
+	if tgt.ReadLimit > 0 {
+		if elapsed := time.Since(bodyStart); elapsed > 0 {
+			tr.Throughput = int64(float64(readTotal) / elapsed.Seconds())
+		}
+		if minThroughput > 0 && tr.Throughput < minThroughput {
+			tr.Class = model.ClassSlow
+			tr.Error = fmt.Sprintf("%d B/s below %d B/s", tr.Throughput, minThroughput)
+			return tr
+		}
+	}
+
+	tr.Passed = true
+	return tr
+}
+
+type pinnedIPKey struct{}
+
+// withPinnedIP makes dialers connect to ip instead of resolving the URL host
+func withPinnedIP(ctx context.Context, ip string) context.Context {
+	if ip == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, pinnedIPKey{}, ip)
+}
+
+// pinAddr replaces the host of addr with the pinned IP, keeping the port
+func pinAddr(ctx context.Context, addr string) string {
+	ip, _ := ctx.Value(pinnedIPKey{}).(string)
+	if ip == "" {
+		return addr
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return net.JoinHostPort(ip, port)
+}
+
+func failure(err error, st *traceState, bytes int) model.TargetResult {
+	tr := model.TargetResult{Bytes: bytes, Class: classify(err, st, bytes), Error: err.Error()}
+	st.fill(&tr)
+	return tr
+}
+
+// isTimeout reports whether the check was cut by a deadline rather than refused.
+func isTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
 }
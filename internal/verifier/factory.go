@@ -1,16 +1,113 @@
 package verifier
 
-import "strings"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+)
 
-func NewVerifier(targetGroup string) Verifier {
-	// targetGroup может быть "google_tcp", "discord_udp" и т.д.
+// TargetsDir — каталог с описаниями групп целей (<group>.json) внутри контейнера
+var TargetsDir = "/app/targets"
 
-	if strings.Contains(targetGroup, "discord") {
-		return &DiscordVerifier{Mode: targetGroup}
+// PayloadDir — каталог с payload-файлами udp-целей (общий с fake для nfqws)
+var PayloadDir = "/app/fake"
+
+// TargetGroup — содержимое файла targets/<group>.json
+type TargetGroup struct {
+	Name    string   `json:"name"`
+	Targets []Target `json:"targets"`
+}
+
+// GroupVerifier — общий верификатор для любой группы целей, описанной данными
+type GroupVerifier struct {
+	Group string
+	Title string
+	List  []Target
+}
+
+func (v *GroupVerifier) Name() string {
+	if v.Title != "" {
+		return v.Title + " (" + v.Group + ")"
+	}
+	return v.Group
+}
+
+func (v *GroupVerifier) Targets() []Target { return v.List }
+
+func (v *GroupVerifier) Run(ctx context.Context, opts RunOptions) CheckResult {
+	return ExecuteChecks(ctx, v.List, opts)
+}
+
+// NewVerifier builds a verifier for the group. Targets passed with the request win,
+// otherwise the group file is loaded from TargetsDir.
+func NewVerifier(targetGroup string, targets []Target) (Verifier, error) {
+	if len(targets) > 0 {
+		if err := compileTargets(targets); err != nil {
+			return nil, err
+		}
+		return &GroupVerifier{Group: targetGroup, List: targets}, nil
 	}
-	if strings.Contains(targetGroup, "google") {
-		return &GoogleVerifier{Mode: targetGroup}
+
+	g, err := LoadGroup(GroupFile(TargetsDir, targetGroup))
+	if err != nil {
+		return nil, err
+	}
+	return &GroupVerifier{Group: targetGroup, Title: g.Name, List: g.Targets}, nil
+}
+
+// GroupFile returns the conventional path of a group definition
+func GroupFile(dir, group string) string {
+	return filepath.Join(dir, group+".json")
+}
+
+// LoadGroup reads and validates a target group file
+func LoadGroup(path string) (TargetGroup, error) {
+	var g TargetGroup
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return g, fmt.Errorf("read targets %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &g); err != nil {
+		return g, fmt.Errorf("parse targets %s: %w", path, err)
+	}
+	if len(g.Targets) == 0 {
+		return g, fmt.Errorf("targets %s: empty target list", path)
+	}
+
+	for i, t := range g.Targets {
+		if t.URL == "" {
+			return g, fmt.Errorf("targets %s: target #%d has no url", path, i)
+		}
+		switch t.Proto {
+		case "", "tcp", "quic":
+			if t.BodyRegex != "" {
+				if _, err := bodyRegex(t.BodyRegex); err != nil {
+					return g, fmt.Errorf("targets %s: %s: body_regex: %w", path, t.URL, err)
+				}
+			}
+			if (t.HeadSHA256 == "") != (t.HeadBytes == 0) {
+				return g, fmt.Errorf("targets %s: %s: head_sha256 and head_bytes go together", path, t.URL)
+			}
+		case ProtoSTUN, ProtoDiscordIP, ProtoUDP:
+			if _, _, err := net.SplitHostPort(t.URL); err != nil {
+				return g, fmt.Errorf("targets %s: %s: udp targets are host:port: %w", path, t.URL, err)
+			}
+			if t.Proto != ProtoUDP {
+				break
+			}
+			if t.Payload == "" {
+				return g, fmt.Errorf("targets %s: %s: udp target has no payload", path, t.URL)
+			}
+			if _, err := parsePattern(t.ReplyPattern); err != nil {
+				return g, fmt.Errorf("targets %s: %s: reply_pattern: %w", path, t.URL, err)
+			}
+		default:
+			return g, fmt.Errorf("targets %s: %s: unknown proto %q", path, t.URL, t.Proto)
+		}
 	}
-	// Default
-	return &GeneralVerifier{Mode: targetGroup}
+	return g, nil
 }
@@ -0,0 +1,163 @@
+package verifier
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"prikop/internal/model"
+	"time"
+)
+
+const (
+	// ModeFreeze measures the byte offset at which TCP transfers stall
+	ModeFreeze = "freeze"
+
+	// FreezeIdle — пауза без данных, после которой передача считается замершей
+	FreezeIdle = 1500 * time.Millisecond
+	// FreezeReadLimit — сколько читаем, чтобы гарантированно пройти окно 16-20KB
+	FreezeReadLimit = 64 * 1024
+
+	// Классическая отсечка ТСПУ: ~16-20KB полезной нагрузки с запасом на TLS-оверхед
+	FreezeWindowMin = 14 * 1024
+	FreezeWindowMax = 24 * 1024
+)
+
+// InFreezeWindow reports whether a transfer stalled within the classic 16-20KB cutoff
+func InFreezeWindow(bytes int) bool {
+	return bytes >= FreezeWindowMin && bytes <= FreezeWindowMax
+}
+
+// checkFreeze downloads a target and records where the transfer froze, if it did.
+// A frozen transfer is reported as ClassStalled with Bytes set to the offset.
+func checkFreeze(ctx context.Context, tgt Target) model.TargetResult {
+	if tgt.Proto == "quic" || IsUDP(tgt.Proto) {
+		return checkTarget(ctx, tgt, 0)
+	}
+
+	reqCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	reqCtx = withPinnedIP(reqCtx, tgt.IP)
+
+	st := newTraceState()
+	reqCtx = httptrace.WithClientTrace(reqCtx, st.clientTrace())
+
+	req, err := http.NewRequestWithContext(reqCtx, "GET", tgt.URL, nil)
+	if err != nil {
+		return failure(err, st, 0)
+	}
+	req.Header.Set("User-Agent", UserAgent)
+
+	resp, err := tcpClient.Do(req)
+	if err != nil {
+		return failure(err, st, 0)
+	}
+	defer resp.Body.Close()
+
+	// Reader goroutine reports progress, the loop below watches for silence
+	chunks := make(chan int)
+	readErr := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 4096)
+		total := 0
+		for total < FreezeReadLimit {
+			n, err := resp.Body.Read(buf)
+			if n > 0 {
+				total += n
+				select {
+				case chunks <- n:
+				case <-reqCtx.Done():
+					return
+				}
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+		readErr <- nil
+	}()
+
+	readTotal := 0
+	lastData := time.Now()
+	idle := time.NewTimer(FreezeIdle)
+	defer idle.Stop()
+
+	// deadline reports the end of the overall time budget: a transfer that was
+	// still receiving data is a slow link, not a freeze
+	deadline := func() model.TargetResult {
+		tr := model.TargetResult{Bytes: readTotal, Status: resp.StatusCode, Error: ctx.Err().Error()}
+		if time.Since(lastData) < FreezeIdle {
+			tr.Class = model.ClassTimeout
+			return tr
+		}
+		tr.Class = model.ClassStalled
+		tr.Cutoff = InFreezeWindow(readTotal)
+		return tr
+	}
+
+	for {
+		select {
+		case n := <-chunks:
+			readTotal += n
+			lastData = time.Now()
+			idle.Reset(FreezeIdle)
+
+		case err := <-readErr:
+			if err != nil && err != io.EOF && ctx.Err() != nil {
+				return deadline()
+			}
+			tr := model.TargetResult{Passed: true, Bytes: readTotal, Status: resp.StatusCode}
+			if err != nil && err != io.EOF {
+				tr = failure(err, st, readTotal)
+				tr.Status = resp.StatusCode
+				tr.Cutoff = InFreezeWindow(readTotal)
+			}
+			return tr
+
+		case <-idle.C:
+			cancel()
+			return model.TargetResult{
+				Bytes:  readTotal,
+				Status: resp.StatusCode,
+				Class:  model.ClassStalled,
+				Error:  fmt.Sprintf("no data for %s", FreezeIdle),
+				Cutoff: InFreezeWindow(readTotal),
+			}
+
+		case <-ctx.Done():
+			return deadline()
+		}
+	}
+}
+
+// FreezeDiagnosis summarizes freeze-mode results for a whole group
+type FreezeDiagnosis struct {
+	Total    int // targets with a usable TCP measurement
+	Frozen   int // transfers that stalled anywhere
+	InWindow int // transfers that stalled inside 16-20KB
+}
+
+// DiagnoseFreeze aggregates per-target freeze results
+func DiagnoseFreeze(targets []model.TargetResult) FreezeDiagnosis {
+	var d FreezeDiagnosis
+	for _, t := range targets {
+		if t.Class == model.ClassCancelled || t.Class == model.ClassDNS || t.Class == model.ClassDial {
+			continue
+		}
+		d.Total++
+		if t.Class == model.ClassStalled {
+			d.Frozen++
+		}
+		if t.Cutoff {
+			d.InWindow++
+		}
+	}
+	return d
+}
+
+// Detected reports the classic cutoff: a noticeable share of transfers dies inside the window
+func (d FreezeDiagnosis) Detected() bool {
+	return d.InWindow > 0 && d.InWindow*4 >= d.Total
+}
@@ -0,0 +1,96 @@
+package verifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"prikop/internal/model"
+)
+
+func TestInFreezeWindow(t *testing.T) {
+	cases := []struct {
+		bytes int
+		want  bool
+	}{
+		{0, false},
+		{FreezeWindowMin - 1, false},
+		{FreezeWindowMin, true},
+		{18 * 1024, true},
+		{FreezeWindowMax, true},
+		{FreezeWindowMax + 1, false},
+	}
+	for _, c := range cases {
+		if got := InFreezeWindow(c.bytes); got != c.want {
+			t.Errorf("InFreezeWindow(%d) = %v, want %v", c.bytes, got, c.want)
+		}
+	}
+}
+
+func TestDiagnoseFreeze(t *testing.T) {
+	frozen := model.TargetResult{Class: model.ClassStalled, Bytes: 16 * 1024, Cutoff: true}
+	stalled := model.TargetResult{Class: model.ClassStalled, Bytes: 40 * 1024}
+	passed := model.TargetResult{Passed: true, Bytes: FreezeReadLimit}
+	dial := model.TargetResult{Class: model.ClassDial}
+
+	cases := []struct {
+		name    string
+		targets []model.TargetResult
+		want    FreezeDiagnosis
+		detect  bool
+	}{
+		{"nothing measured", []model.TargetResult{dial, {Class: model.ClassDNS}}, FreezeDiagnosis{}, false},
+		{"all pass", []model.TargetResult{passed, passed}, FreezeDiagnosis{Total: 2}, false},
+		{"stall outside the window", []model.TargetResult{stalled, passed}, FreezeDiagnosis{Total: 2, Frozen: 1}, false},
+		{"cutoff", []model.TargetResult{frozen, passed, passed, dial}, FreezeDiagnosis{Total: 3, Frozen: 1, InWindow: 1}, true},
+		{"rare cutoff", []model.TargetResult{frozen, passed, passed, passed, passed}, FreezeDiagnosis{Total: 5, Frozen: 1, InWindow: 1}, false},
+	}
+	for _, c := range cases {
+		d := DiagnoseFreeze(c.targets)
+		if d != c.want || d.Detected() != c.detect {
+			t.Errorf("%s: %+v detected=%v, want %+v detected=%v", c.name, d, d.Detected(), c.want, c.detect)
+		}
+	}
+}
+
+// TestCheckFreeze serves a full body and one that goes silent inside the window
+func TestCheckFreeze(t *testing.T) {
+	initOnce.Do(initClients)
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/full", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, FreezeReadLimit))
+	})
+	mux.HandleFunc("/cutoff", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 16*1024))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	defer close(release)
+
+	cases := []struct {
+		path   string
+		passed bool
+		class  string
+		cutoff bool
+	}{
+		{"/full", true, "", false},
+		{"/cutoff", false, model.ClassStalled, true},
+	}
+	for _, c := range cases {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		tr := checkFreeze(ctx, Target{URL: srv.URL + c.path})
+		cancel()
+		if tr.Passed != c.passed || tr.Class != c.class || tr.Cutoff != c.cutoff {
+			t.Errorf("%s: passed=%v class=%q cutoff=%v bytes=%d, want passed=%v class=%q cutoff=%v",
+				c.path, tr.Passed, tr.Class, tr.Cutoff, tr.Bytes, c.passed, c.class, c.cutoff)
+		}
+	}
+}
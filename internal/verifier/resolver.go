@@ -0,0 +1,284 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"prikop/internal/model"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	ResolverSystem = "system"
+	ResolverDoH    = "doh"
+	ResolverDoT    = "dot"
+
+	DefaultDoH = "https://1.1.1.1/dns-query"
+	DefaultDoT = "1.1.1.1:853"
+
+	// resolverCacheTTL — ответы кешируются в воркере между прогонами стратегий
+	resolverCacheTTL = 5 * time.Minute
+)
+
+// Resolver resolves target hosts to IPv4 addresses instead of the container resolver
+type Resolver interface {
+	Name() string
+	LookupIP(ctx context.Context, host string) ([]string, error)
+}
+
+var (
+	resolversMu sync.Mutex
+	resolvers   = make(map[string]Resolver)
+)
+
+// ResolverFor parses a resolver spec: "" or "system", "doh[:<url>]", "dot[:<host:port>]".
+// The system resolver is returned as nil: dialers then resolve names themselves.
+// Resolvers are shared per spec so their caches live as long as the worker.
+func ResolverFor(spec string) (Resolver, error) {
+	if spec == "" || spec == ResolverSystem {
+		return nil, nil
+	}
+
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	if r, ok := resolvers[spec]; ok {
+		return r, nil
+	}
+
+	kind, arg, _ := strings.Cut(spec, ":")
+	var r Resolver
+	switch kind {
+	case ResolverDoH:
+		if arg == "" {
+			arg = DefaultDoH
+		}
+		r = &dohResolver{url: arg, client: &http.Client{
+			Timeout:   HardTimeout,
+			Transport: &http.Transport{DialContext: dnsDialer().DialContext, ForceAttemptHTTP2: true},
+		}}
+	case ResolverDoT:
+		if arg == "" {
+			arg = DefaultDoT
+		}
+		r = &dotResolver{addr: arg}
+	default:
+		return nil, fmt.Errorf("unknown resolver %q", spec)
+	}
+
+	r = &cachingResolver{Resolver: r, entries: make(map[string]cacheEntry)}
+	resolvers[spec] = r
+	return r, nil
+}
+
+// dnsDialer marks resolver sockets so the worker firewall lets them bypass nfqws:
+// a desynced DoH request would look like a DNS failure of the target.
+func dnsDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout: HardTimeout,
+		Control: func(_, _ string, c syscall.RawConn) error {
+			var serr error
+			err := c.Control(func(fd uintptr) {
+				serr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_MARK, model.DNSMark)
+			})
+			if err != nil {
+				return err
+			}
+			return serr
+		},
+	}
+}
+
+type dohResolver struct {
+	url    string
+	client *http.Client
+}
+
+func (r *dohResolver) Name() string { return "doh " + r.url }
+
+func (r *dohResolver) LookupIP(ctx context.Context, host string) ([]string, error) {
+	query, id, err := buildQuery(host)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", r.url, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, dnsError(host, r, err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, dnsError(host, r, resp.Status)
+	}
+
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, dnsError(host, r, err.Error())
+	}
+	return parseAnswer(host, r, answer, id)
+}
+
+type dotResolver struct {
+	addr string
+}
+
+func (r *dotResolver) Name() string { return "dot " + r.addr }
+
+func (r *dotResolver) LookupIP(ctx context.Context, host string) ([]string, error) {
+	query, id, err := buildQuery(host)
+	if err != nil {
+		return nil, err
+	}
+
+	serverName, _, _ := net.SplitHostPort(r.addr)
+	d := tls.Dialer{NetDialer: dnsDialer(), Config: &tls.Config{ServerName: serverName, InsecureSkipVerify: net.ParseIP(serverName) != nil}}
+	conn, err := d.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return nil, dnsError(host, r, err.Error())
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// DNS over TCP framing: 2-byte length prefix
+	msg := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+	if _, err := conn.Write(append(msg, query...)); err != nil {
+		return nil, dnsError(host, r, err.Error())
+	}
+
+	var size [2]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, dnsError(host, r, err.Error())
+	}
+	answer := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(conn, answer); err != nil {
+		return nil, dnsError(host, r, err.Error())
+	}
+	return parseAnswer(host, r, answer, id)
+}
+
+// buildQuery packs an A query with recursion desired
+func buildQuery(host string) ([]byte, uint16, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return nil, 0, err
+	}
+	id := uint16(rand.Uint32())
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := msg.Pack()
+	return packed, id, err
+}
+
+// parseAnswer extracts A records, CNAME chains are already followed by the server
+func parseAnswer(host string, r Resolver, answer []byte, id uint16) ([]string, error) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(answer); err != nil {
+		return nil, dnsError(host, r, err.Error())
+	}
+	if msg.Header.ID != id {
+		return nil, dnsError(host, r, "response id mismatch")
+	}
+	if msg.Header.RCode == dnsmessage.RCodeNameError {
+		return nil, &net.DNSError{Err: "no such host", Name: host, Server: r.Name(), IsNotFound: true}
+	}
+	if msg.Header.RCode != dnsmessage.RCodeSuccess {
+		return nil, dnsError(host, r, msg.Header.RCode.String())
+	}
+
+	var ips []string
+	for _, rr := range msg.Answers {
+		if a, ok := rr.Body.(*dnsmessage.AResource); ok {
+			ips = append(ips, net.IP(a.A[:]).String())
+		}
+	}
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no A records", Name: host, Server: r.Name(), IsNotFound: true}
+	}
+	return ips, nil
+}
+
+// dnsError wraps resolver failures so classify() reports them as ClassDNS
+func dnsError(host string, r Resolver, msg string) error {
+	return &net.DNSError{Err: msg, Name: host, Server: r.Name()}
+}
+
+type cacheEntry struct {
+	ips     []string
+	expires time.Time
+}
+
+type cachingResolver struct {
+	Resolver
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func (c *cachingResolver) LookupIP(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	e, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		return e.ips, nil
+	}
+
+	ips, err := c.Resolver.LookupIP(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[host] = cacheEntry{ips: ips, expires: time.Now().Add(resolverCacheTTL)}
+	c.mu.Unlock()
+	return ips, nil
+}
+
+type resolverKey struct{}
+
+// withResolver makes dialers resolve target hosts through r (nil keeps the system resolver)
+func withResolver(ctx context.Context, r Resolver) context.Context {
+	if r == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, resolverKey{}, r)
+}
+
+// dialAddr returns the address to dial: the pinned IP wins, then the phase resolver,
+// otherwise addr is left for the system resolver
+func dialAddr(ctx context.Context, addr string) (string, error) {
+	if ip, _ := ctx.Value(pinnedIPKey{}).(string); ip != "" {
+		return pinAddr(ctx, addr), nil
+	}
+	r, _ := ctx.Value(resolverKey{}).(Resolver)
+	if r == nil {
+		return addr, nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return addr, nil
+	}
+	ips, err := r.LookupIP(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(ips[0], port), nil
+}
@@ -0,0 +1,59 @@
+package verifier
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+)
+
+const (
+	wgInitiationLen = 148
+	wgResponseLen   = 92
+)
+
+// ServeWireGuard runs a WireGuard responder stand-in: every handshake initiation
+// is answered with the response template (fake/wireguard_response.bin) addressed
+// to the initiator's sender index. No crypto is done — it only lets udp targets
+// with the wireguard payload be checked end to end without a real server.
+func ServeWireGuard(ctx context.Context, addr string, template []byte) error {
+	if len(template) != wgResponseLen {
+		return fmt.Errorf("wireguard response template is %d bytes, expected %d", len(template), wgResponseLen)
+	}
+
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(">>> WireGuard responder listening on %s\n", conn.LocalAddr())
+	return serveWireGuard(ctx, conn, template)
+}
+
+// serveWireGuard answers handshake initiations on conn until ctx is done
+func serveWireGuard(ctx context.Context, conn net.PacketConn, template []byte) error {
+	defer conn.Close()
+	context.AfterFunc(ctx, func() { conn.Close() })
+
+	buf := make([]byte, 2048)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		// Handshake initiation: type 1, 3 reserved zero bytes
+		if n != wgInitiationLen || binary.LittleEndian.Uint32(buf[0:4]) != 1 {
+			continue
+		}
+
+		resp := append([]byte(nil), template...)
+		binary.LittleEndian.PutUint32(resp[4:8], rand.Uint32()) // sender index
+		copy(resp[8:12], buf[4:8])                              // receiver = initiator's sender index
+		if _, err := conn.WriteTo(resp, peer); err != nil {
+			fmt.Printf(">>> WireGuard responder: %v\n", err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package verifier
 
 import (
 	"context"
+	"prikop/internal/model"
 )
 
 // CheckResult результат проверки одной группы целей
@@ -12,19 +13,50 @@ type CheckResult struct {
 	Details      string
 	PassedUrls   []string
 	FailedUrls   []string
+	Timeouts     int     // провалы по таймауту (для адаптивного пула)
+	Raced        bool    // проверки прерваны в режиме гонки
+	Weight       float64 // сумма весов пройденных целей
+	TotalWeight  float64
+	Targets      []model.TargetResult
+}
+
+// Reporter получает результат каждой цели сразу по завершении проверки
+type Reporter func(model.TargetResult)
+
+// RunOptions управляет прогоном проверок
+type RunOptions struct {
+	Report Reporter
+	// MinWeight > 0 включает режим гонки: оставшиеся проверки отменяются,
+	// как только набрать вес пройденных целей MinWeight уже невозможно
+	MinWeight float64
+	// Mode: "" — обычные проверки, ModeFreeze — поиск смещения заморозки, ModeDNS — сверка резолверов
+	Mode string
+	// MinThroughput (байт/с) применяется к целям с ReadLimit
+	MinThroughput int64
+	// Resolver резолвит цели вместо системного резолвера (nil — системный)
+	Resolver Resolver
 }
 
 // Verifier интерфейс для всех тест-кейсов
 type Verifier interface {
 	Name() string
+	// Targets возвращает список целей группы
+	Targets() []Target
 	// Run запускает проверку. Должен вызываться ВНУТРИ контейнера.
-	Run(ctx context.Context) CheckResult
+	Run(ctx context.Context, opts RunOptions) CheckResult
 }
 
-// Target структура цели для проверки
-type Target struct {
-	URL          string
-	Threshold    int    // байт для успеха
-	Proto        string // tcp, udp, quic
-	IgnoreStatus bool
+// Target структура цели для проверки (описание в targets/<group>.json)
+type Target = model.Target
+
+// Протоколы целей помимо tcp (по умолчанию) и quic
+const (
+	ProtoSTUN      = "stun"       // STUN binding request на host:port
+	ProtoDiscordIP = "discord_ip" // Discord voice IP discovery на host:port
+	ProtoUDP       = "udp"        // произвольный запрос из Payload, ответ по ReplyLen/ReplyPattern
+)
+
+// IsUDP reports whether the proto is a raw UDP exchange addressed as host:port
+func IsUDP(proto string) bool {
+	return proto == ProtoSTUN || proto == ProtoDiscordIP || proto == ProtoUDP
 }
@@ -0,0 +1,215 @@
+package verifier
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"prikop/internal/model"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// UDPRetransmit — интервал повторной отправки запроса, пока нет ответа
+	UDPRetransmit = time.Second
+
+	stunMagicCookie = 0x2112A442
+
+	// Discord IP discovery: type(2) + length(2) + SSRC(4) + address(64) + port(2)
+	discordIPLen      = 74
+	discordIPRequest  = 0x0001
+	discordIPResponse = 0x0002
+)
+
+// udpExchange sends payload to the target until a datagram arrives or the check times out.
+// accept validates the reply and returns a mismatch description, "" if the reply is good.
+func udpExchange(ctx context.Context, tgt Target, payload []byte, accept func([]byte) string) model.TargetResult {
+	ctx, cancel := context.WithTimeout(ctx, HardTimeout)
+	defer cancel()
+
+	start := time.Now()
+	addr, err := dialAddr(withPinnedIP(ctx, tgt.IP), tgt.URL)
+	if err != nil {
+		return udpFailure(err)
+	}
+	d := net.Dialer{}
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return udpFailure(err)
+	}
+	defer conn.Close()
+
+	// Cancellation (racing, abort) must interrupt a blocked read
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	buf := make([]byte, 2048)
+	for {
+		if ctx.Err() != nil {
+			return udpFailure(ctx.Err())
+		}
+		if _, err := conn.Write(payload); err != nil {
+			return udpFailure(err)
+		}
+
+		conn.SetReadDeadline(time.Now().Add(UDPRetransmit))
+		n, err := conn.Read(buf)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() && ctx.Err() == nil {
+				continue // lost datagram, send again
+			}
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			return udpFailure(err)
+		}
+
+		tr := model.TargetResult{Bytes: n, TTFBMs: time.Since(start).Milliseconds()}
+		if msg := accept(buf[:n]); msg != "" {
+			tr.Class = model.ClassContent
+			tr.Error = msg
+			return tr
+		}
+		tr.Passed = true
+		return tr
+	}
+}
+
+// udpFailure classifies UDP errors: there is no handshake, only "answered or not"
+func udpFailure(err error) model.TargetResult {
+	var dnsErr *net.DNSError
+
+	class := model.ClassError
+	switch {
+	case errors.Is(err, context.Canceled):
+		class = model.ClassCancelled
+	case errors.As(err, &dnsErr):
+		class = model.ClassDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		// ICMP port unreachable, real or injected
+		class = model.ClassReset
+	case isTimeout(err):
+		class = model.ClassTimeout
+	}
+	return model.TargetResult{Class: class, Error: err.Error()}
+}
+
+// checkSTUN sends a STUN binding request and expects a binding response
+func checkSTUN(ctx context.Context, tgt Target) model.TargetResult {
+	req := make([]byte, 20)
+	binary.BigEndian.PutUint16(req[0:2], 0x0001) // Binding Request
+	binary.BigEndian.PutUint16(req[2:4], 0x0000) // Length
+	binary.BigEndian.PutUint32(req[4:8], stunMagicCookie)
+	rand.Read(req[8:20]) // Transaction ID
+
+	return udpExchange(ctx, tgt, req, func(resp []byte) string {
+		if len(resp) < 20 {
+			return fmt.Sprintf("STUN reply of %d bytes", len(resp))
+		}
+		if msgType := binary.BigEndian.Uint16(resp[0:2]); msgType != 0x0101 && msgType != 0x0111 {
+			return fmt.Sprintf("STUN message type %#04x", msgType)
+		}
+		if string(resp[8:20]) != string(req[8:20]) {
+			return "STUN transaction id mismatch"
+		}
+		return ""
+	})
+}
+
+// checkDiscordIP performs Discord voice IP discovery, the first packet a client sends
+// to a voice server (same layout as fake/discord-ip-discovery-*.bin).
+// Voice servers only answer SSRCs of an established session, so such targets need
+// the endpoint and SSRC from a live voice connection.
+func checkDiscordIP(ctx context.Context, tgt Target) model.TargetResult {
+	req := make([]byte, discordIPLen)
+	binary.BigEndian.PutUint16(req[0:2], discordIPRequest)
+	binary.BigEndian.PutUint16(req[2:4], discordIPLen-4)
+	ssrc := tgt.SSRC
+	if ssrc == 0 {
+		ssrc = rand.Uint32()
+	}
+	binary.BigEndian.PutUint32(req[4:8], ssrc)
+
+	return udpExchange(ctx, tgt, req, func(resp []byte) string {
+		if len(resp) != discordIPLen {
+			return fmt.Sprintf("IP discovery reply of %d bytes", len(resp))
+		}
+		if binary.BigEndian.Uint16(resp[0:2]) != discordIPResponse {
+			return fmt.Sprintf("IP discovery type %#04x", binary.BigEndian.Uint16(resp[0:2]))
+		}
+		if binary.BigEndian.Uint32(resp[4:8]) != ssrc {
+			return "IP discovery SSRC mismatch"
+		}
+		return ""
+	})
+}
+
+// checkUDP sends the payload file and matches the reply by length and byte pattern
+func checkUDP(ctx context.Context, tgt Target) model.TargetResult {
+	path := tgt.Payload
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(PayloadDir, path)
+	}
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return model.TargetResult{Class: model.ClassError, Error: err.Error()}
+	}
+	pattern, err := parsePattern(tgt.ReplyPattern)
+	if err != nil {
+		return model.TargetResult{Class: model.ClassError, Error: err.Error()}
+	}
+
+	return udpExchange(ctx, tgt, payload, func(resp []byte) string {
+		if tgt.ReplyLen > 0 && len(resp) != tgt.ReplyLen {
+			return fmt.Sprintf("reply of %d bytes, expected %d", len(resp), tgt.ReplyLen)
+		}
+		if !matchPattern(pattern, resp) {
+			return fmt.Sprintf("reply %x does not match %s", resp[:min(len(resp), len(pattern))], tgt.ReplyPattern)
+		}
+		return ""
+	})
+}
+
+// parsePattern parses a hex byte pattern, "??" matches any byte, spaces are ignored.
+// Wildcards are returned as -1.
+func parsePattern(s string) ([]int, error) {
+	s = strings.ReplaceAll(s, " ", "")
+	if len(s)%2 != 0 {
+		return nil, fmt.Errorf("odd length pattern %q", s)
+	}
+
+	pattern := make([]int, 0, len(s)/2)
+	for i := 0; i < len(s); i += 2 {
+		if s[i:i+2] == "??" {
+			pattern = append(pattern, -1)
+			continue
+		}
+		b, err := strconv.ParseUint(s[i:i+2], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("bad byte %q in pattern", s[i:i+2])
+		}
+		pattern = append(pattern, int(b))
+	}
+	return pattern, nil
+}
+
+// matchPattern reports whether data starts with the pattern
+func matchPattern(pattern []int, data []byte) bool {
+	if len(data) < len(pattern) {
+		return false
+	}
+	for i, b := range pattern {
+		if b >= 0 && int(data[i]) != b {
+			return false
+		}
+	}
+	return true
+}
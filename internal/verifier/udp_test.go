@@ -0,0 +1,89 @@
+package verifier
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"prikop/internal/model"
+)
+
+func TestParsePattern(t *testing.T) {
+	pattern, err := parsePattern("02 ?? 00ff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{0x02, -1, 0x00, 0xff}
+	if len(pattern) != len(want) {
+		t.Fatalf("pattern %v, want %v", pattern, want)
+	}
+	for i := range want {
+		if pattern[i] != want[i] {
+			t.Fatalf("pattern %v, want %v", pattern, want)
+		}
+	}
+
+	for _, bad := range []string{"0", "zz", "02?"} {
+		if _, err := parsePattern(bad); err == nil {
+			t.Errorf("parsePattern(%q) accepted", bad)
+		}
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	pattern, _ := parsePattern("02??00")
+	cases := []struct {
+		data []byte
+		want bool
+	}{
+		{[]byte{0x02, 0x55, 0x00, 0x01}, true},
+		{[]byte{0x02, 0xaa, 0x00}, true},
+		{[]byte{0x02, 0x55, 0x01}, false},
+		{[]byte{0x01, 0x55, 0x00}, false},
+		{[]byte{0x02, 0x55}, false},
+	}
+	for _, c := range cases {
+		if got := matchPattern(pattern, c.data); got != c.want {
+			t.Errorf("matchPattern(%x) = %v, want %v", c.data, got, c.want)
+		}
+	}
+}
+
+// TestCheckUDPWireGuard runs the wireguard udp target against the responder stand-in
+func TestCheckUDPWireGuard(t *testing.T) {
+	fake, err := filepath.Abs("../../fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	template, err := os.ReadFile(filepath.Join(fake, "wireguard_response.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go serveWireGuard(ctx, conn, template)
+
+	tgt := Target{
+		URL:          conn.LocalAddr().String(),
+		Proto:        ProtoUDP,
+		Payload:      filepath.Join(fake, "wireguard_initiation.bin"),
+		ReplyLen:     wgResponseLen,
+		ReplyPattern: "02000000",
+	}
+	if tr := checkUDP(ctx, tgt); !tr.Passed {
+		t.Fatalf("handshake failed: %s %s", tr.Class, tr.Error)
+	}
+
+	// A reply of another length is a content mismatch, not a pass
+	tgt.ReplyLen = wgResponseLen + 1
+	if tr := checkUDP(ctx, tgt); tr.Passed || tr.Class != model.ClassContent {
+		t.Fatalf("wrong reply length: passed=%v class=%q", tr.Passed, tr.Class)
+	}
+}
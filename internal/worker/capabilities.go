@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"net"
+	"os/exec"
+	"prikop/internal/model"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	caps     model.WorkerCapabilities
+	capsOnce sync.Once
+
+	optionRe = regexp.MustCompile(`--([a-z0-9][a-z0-9-]*)`)
+)
+
+// Capabilities probes the container environment once and caches the result
+func Capabilities() model.WorkerCapabilities {
+	capsOnce.Do(func() {
+		caps = model.WorkerCapabilities{
+			ProtocolVersion: model.ProtocolVersion,
+			NfqwsVersion:    nfqwsVersion(),
+			Options:         nfqwsOptions(),
+			Firewall:        firewallBackend(),
+			IPv6:            hasIPv6(),
+		}
+	})
+	return caps
+}
+
+func nfqwsVersion() string {
+	// nfqws exits non-zero on --version in some builds, output is still useful
+	out, _ := exec.Command("/usr/bin/nfqws", "--version").CombinedOutput()
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	if line == "" {
+		return "unknown"
+	}
+	return line
+}
+
+func nfqwsOptions() []string {
+	out, _ := exec.Command("/usr/bin/nfqws", "--help").CombinedOutput()
+
+	seen := make(map[string]bool)
+	var opts []string
+	for _, m := range optionRe.FindAllStringSubmatch(string(out), -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			opts = append(opts, m[1])
+		}
+	}
+	sort.Strings(opts)
+	return opts
+}
+
+func firewallBackend() string {
+	out, err := exec.Command("iptables", "-V").CombinedOutput()
+	if err != nil {
+		if _, err := exec.LookPath("nft"); err == nil {
+			return "nftables"
+		}
+		return "none"
+	}
+	switch v := string(out); {
+	case strings.Contains(v, "nf_tables"):
+		return "iptables-nft"
+	case strings.Contains(v, "legacy"):
+		return "iptables-legacy"
+	default:
+		return "iptables"
+	}
+}
+
+// hasIPv6 checks for a global unicast IPv6 address on any interface
+func hasIPv6() bool {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok || ipNet.IP.To4() != nil {
+			continue
+		}
+		if ipNet.IP.IsGlobalUnicast() {
+			return true
+		}
+	}
+	return false
+}
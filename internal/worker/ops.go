@@ -3,14 +3,20 @@ package worker
 import (
 	"bytes"
 	"fmt"
+	"net"
 	"os/exec"
 	"prikop/internal/model"
+	"prikop/internal/verifier"
+	"strconv"
 	"strings"
 	"syscall"
 )
 
-// SetupIptables applies rules based on target group
-func SetupIptables(group string) error {
+// udpQueuePorts — UDP-порты, которые всегда идут в nfqws: QUIC, STUN, голосовые/VPN
+var udpQueuePorts = []string{"443", "19294:19344", "50000:65535"}
+
+// SetupIptables queues the traffic of the targets to nfqws
+func SetupIptables(targets []model.Target) error {
 	// Flush previous rules
 	_ = exec.Command("iptables", "-F", "OUTPUT").Run()
 
@@ -22,13 +28,64 @@ func SetupIptables(group string) error {
 		return fmt.Errorf("tcp rule: %s", out)
 	}
 	// UDP
-	argsUDP := []string{"-I", "OUTPUT", "-p", "udp", "-m", "multiport", "--dports", "443,50000:65535", "-j", "NFQUEUE", "--queue-num", model.QueueNum, "--queue-bypass"}
+	argsUDP := []string{"-I", "OUTPUT", "-p", "udp", "-m", "multiport", "--dports", strings.Join(udpQueuePorts, ","), "-j", "NFQUEUE", "--queue-num", model.QueueNum, "--queue-bypass"}
 	if out, err := exec.Command("iptables", argsUDP...).CombinedOutput(); err != nil {
 		return fmt.Errorf("udp rule: %s", out)
 	}
+	// UDP-цели на прочих портах (DHT, ISAKMP, WireGuard...) иначе прошли бы мимо nfqws
+	for _, port := range targetUDPPorts(targets) {
+		argsPort := []string{"-I", "OUTPUT", "-p", "udp", "--dport", port, "-j", "NFQUEUE", "--queue-num", model.QueueNum, "--queue-bypass"}
+		if out, err := exec.Command("iptables", argsPort...).CombinedOutput(); err != nil {
+			return fmt.Errorf("udp rule %s: %s", port, out)
+		}
+	}
+	// DoH/DoT запросы резолвера идут мимо nfqws (вставляется первым правилом)
+	mark := fmt.Sprintf("%#x/%#x", model.DNSMark, model.DNSMark)
+	argsDNS := []string{"-I", "OUTPUT", "-m", "mark", "--mark", mark, "-j", "RETURN"}
+	if out, err := exec.Command("iptables", argsDNS...).CombinedOutput(); err != nil {
+		return fmt.Errorf("dns rule: %s", out)
+	}
 	return nil
 }
 
+// targetUDPPorts returns the ports of UDP targets not covered by udpQueuePorts
+func targetUDPPorts(targets []model.Target) []string {
+	var ports []string
+	seen := make(map[int]bool)
+	for _, t := range targets {
+		if !verifier.IsUDP(t.Proto) {
+			continue
+		}
+		_, p, err := net.SplitHostPort(t.URL)
+		if err != nil {
+			continue
+		}
+		port, err := strconv.Atoi(p)
+		if err != nil || seen[port] || queuedUDP(port) {
+			continue
+		}
+		seen[port] = true
+		ports = append(ports, p)
+	}
+	return ports
+}
+
+// queuedUDP reports whether udpQueuePorts already covers the port
+func queuedUDP(port int) bool {
+	for _, spec := range udpQueuePorts {
+		lo, hi, isRange := strings.Cut(spec, ":")
+		from, _ := strconv.Atoi(lo)
+		to := from
+		if isRange {
+			to, _ = strconv.Atoi(hi)
+		}
+		if port >= from && port <= to {
+			return true
+		}
+	}
+	return false
+}
+
 // Cleanup removes processes and flushes firewall
 func Cleanup() {
 	_ = exec.Command("pkill", "-9", "nfqws").Run()
@@ -1,13 +1,16 @@
 package worker
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net"
 	"os"
+	"os/exec"
 	"prikop/internal/model"
 	"prikop/internal/verifier"
+	"sync"
 	"time"
 )
 
@@ -25,7 +28,8 @@ func RunWorkerServer(socketPath string) {
 	}
 	defer listener.Close()
 
-	fmt.Printf("Worker listening on %s\n", socketPath)
+	c := Capabilities()
+	fmt.Printf("Worker listening on %s (protocol v%d, %s, %s)\n", socketPath, c.ProtocolVersion, c.NfqwsVersion, c.Firewall)
 
 	for {
 		conn, err := listener.Accept()
@@ -42,55 +46,127 @@ func RunWorkerServer(socketPath string) {
 func handleConnection(conn net.Conn) {
 	defer conn.Close()
 
+	dec := json.NewDecoder(conn)
 	var req model.WorkerRequest
-	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+	if err := dec.Decode(&req); err != nil {
 		sendError(conn, fmt.Sprintf("bad request: %v", err))
 		return
 	}
 
+	switch {
+	case req.Type == model.RequestHello:
+		c := Capabilities()
+		_ = json.NewEncoder(conn).Encode(model.WorkerResult{Success: true, Capabilities: &c})
+		return
+	case req.Version != model.ProtocolVersion:
+		sendError(conn, fmt.Sprintf("protocol mismatch: worker v%d, request v%d", model.ProtocolVersion, req.Version))
+		return
+	case req.Type == model.RequestPing:
+		_ = json.NewEncoder(conn).Encode(model.WorkerResult{Success: true})
+		return
+	}
+
+	// Run requests are answered with a stream of NDJSON events
+	enc := json.NewEncoder(conn)
+	var encMu sync.Mutex
+	emit := func(ev model.WorkerEvent) {
+		encMu.Lock()
+		defer encMu.Unlock()
+		if err := enc.Encode(ev); err != nil {
+			fmt.Fprintf(os.Stderr, "write event error: %v\n", err)
+		}
+	}
+
+	// The orchestrator closes the connection to abort a hopeless evaluation
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		var discard json.RawMessage
+		_ = dec.Decode(&discard)
+		cancel()
+	}()
+
 	// Ensure clean state before running
 	Cleanup()
 	defer Cleanup()
 
-	res := executeTest(req)
+	res := executeTest(ctx, req, emit)
+	res.Aborted = res.Aborted || ctx.Err() != nil
 
-	if err := json.NewEncoder(conn).Encode(res); err != nil {
-		fmt.Fprintf(os.Stderr, "write response error: %v\n", err)
-	}
+	emit(model.WorkerEvent{Event: model.EventResult, Result: &res})
 }
 
-func executeTest(req model.WorkerRequest) model.WorkerResult {
-	if err := SetupIptables(req.TargetGroup); err != nil {
-		return model.WorkerResult{Error: fmt.Sprintf("iptables: %v", err)}
+func executeTest(ctx context.Context, req model.WorkerRequest, emit func(model.WorkerEvent)) model.WorkerResult {
+	v, err := verifier.NewVerifier(req.TargetGroup, req.Targets)
+	if err != nil {
+		return model.WorkerResult{Error: fmt.Sprintf("verifier: %v", err)}
 	}
-
-	cmd, stdout := StartNFQWS(req.StrategyArgs)
-	if cmd == nil {
-		return model.WorkerResult{Error: "nfqws start failed"}
+	resolver, err := verifier.ResolverFor(req.Resolver)
+	if err != nil {
+		return model.WorkerResult{Error: fmt.Sprintf("resolver: %v", err)}
 	}
-	defer KillCmd(cmd)
 
-	// Short delay to let nfqws initialize
-	time.Sleep(50 * time.Millisecond)
-	if cmd.ProcessState != nil && cmd.ProcessState.Exited() {
-		return model.WorkerResult{Error: fmt.Sprintf("nfqws crashed: %s", stdout.String())}
+	// Baseline runs the checks on a clean network: no NFQUEUE rules, no nfqws
+	var cmd *exec.Cmd
+	if !req.Baseline {
+		if err := SetupIptables(v.Targets()); err != nil {
+			return model.WorkerResult{Error: fmt.Sprintf("iptables: %v", err), Infra: true}
+		}
+
+		var stdout *bytes.Buffer
+		cmd, stdout = StartNFQWS(req.StrategyArgs)
+		if cmd == nil {
+			return model.WorkerResult{Error: "nfqws start failed"}
+		}
+
+		// Short delay to let nfqws initialize
+		time.Sleep(50 * time.Millisecond)
+		if cmd.ProcessState != nil && cmd.ProcessState.Exited() {
+			KillCmd(cmd)
+			return model.WorkerResult{Error: fmt.Sprintf("nfqws crashed: %s", stdout.String())}
+		}
 	}
+	emit(model.WorkerEvent{
+		Event:  model.EventNfqwsStarted,
+		Total:  len(v.Targets()),
+		Weight: verifier.TotalWeight(v.Targets()),
+	})
 
-	v := verifier.NewVerifier(req.TargetGroup)
-	ctx, cancel := context.WithTimeout(context.Background(), model.CheckTimeout)
+	checkCtx, cancel := context.WithTimeout(ctx, model.CheckTimeout)
 	defer cancel()
 
-	checkRes := v.Run(ctx)
+	checkStart := time.Now()
+	checkRes := v.Run(checkCtx, verifier.RunOptions{
+		MinWeight:     req.MinWeight,
+		Mode:          req.CheckMode,
+		MinThroughput: req.MinThroughput,
+		Resolver:      resolver,
+		Report: func(tr model.TargetResult) {
+			emit(model.WorkerEvent{Event: model.EventTarget, Target: &tr})
+		},
+	})
+	checkMs := time.Since(checkStart).Milliseconds()
+
+	KillCmd(cmd)
+	emit(model.WorkerEvent{Event: model.EventNfqwsExited})
 
 	return model.WorkerResult{
 		Success:      checkRes.Success,
 		SuccessCount: checkRes.SuccessCount,
 		TotalCount:   checkRes.TotalCount,
+		Weight:       checkRes.Weight,
+		TotalWeight:  checkRes.TotalWeight,
 		Passed:       checkRes.PassedUrls,
 		Failed:       checkRes.FailedUrls,
+		Timeouts:     checkRes.Timeouts,
+		Targets:      checkRes.Targets,
+		Aborted:      checkRes.Raced,
+		CheckMs:      checkMs,
 	}
 }
 
+// sendError rejects a request before it runs with a plain WorkerResult, not an event
+// stream: the orchestrator reports such a reply to a run request as an incompatible worker
 func sendError(conn net.Conn, msg string) {
 	_ = json.NewEncoder(conn).Encode(model.WorkerResult{Error: msg})
 }